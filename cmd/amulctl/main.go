@@ -0,0 +1,210 @@
+// amulctl queries the Amul shop directly for ad-hoc stock lookups, without running the notifier.
+package main
+
+import (
+	"amul-notifier/internal/config"
+	"amul-notifier/pkg/amulclient"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `Usage:
+  amulctl products list [--store=gujarat] [--category=protein] [--watch] [--interval=1m]
+  amulctl check SKU [SKU...] [--store=gujarat] [--category=protein] [--watch] [--interval=1m]
+  amulctl pincode PINCODE
+`
+
+// Shop the commands query, amulclient.DefaultBaseURL when empty
+var shopURL = ""
+
+// ANSI sequence moving the cursor home and clearing the screen, for redrawing watched tables
+const clearScreen = "\033[H\033[2J"
+
+func main() {
+	// Ctrl+C ends a --watch cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "amulctl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, output io.Writer) error {
+	switch {
+	case len(args) >= 2 && args[0] == "products" && args[1] == "list":
+		return listProducts(ctx, args[2:], output)
+	case len(args) >= 1 && args[0] == "check":
+		return checkSKUs(ctx, args[1:], output)
+	case len(args) >= 1 && args[0] == "pincode":
+		return resolvePincode(ctx, args[1:], output)
+	case len(args) >= 1 && args[0] == "subs":
+		return fmt.Errorf("subscriptions are not stored anywhere, monitored SKUs are set with --monitored-skus or MONITORED_SKUS")
+	default:
+		return fmt.Errorf("unknown command\n%s", usage)
+	}
+}
+
+// Flags shared by the product commands
+type productFlags struct {
+	store    string
+	category string
+	watch    bool
+	interval time.Duration
+}
+
+// parseProductFlags parses the product command flags, returning the remaining arguments
+func parseProductFlags(name string, args []string) (productFlags, []string, error) {
+	flagSet := flag.NewFlagSet(name, flag.ContinueOnError)
+	storePtr := flagSet.String("store", config.DefaultStore, "Amul store code, usually the lowercase state name")
+	categoryPtr := flagSet.String("category", "protein", "product category to list")
+	watchPtr := flagSet.Bool("watch", false, "redraw the table every interval until interrupted")
+	intervalPtr := flagSet.Duration("interval", time.Minute, "refresh interval with --watch")
+
+	// Flags may come before or after the SKUs
+	positional := []string{}
+	for len(args) > 0 {
+		if err := flagSet.Parse(args); err != nil {
+			return productFlags{}, nil, err
+		}
+		if flagSet.NArg() == 0 {
+			break
+		}
+		positional = append(positional, flagSet.Arg(0))
+		args = flagSet.Args()[1:]
+	}
+	if *intervalPtr <= 0 {
+		return productFlags{}, nil, fmt.Errorf("interval must be positive")
+	}
+	return productFlags{store: *storePtr, category: *categoryPtr, watch: *watchPtr, interval: *intervalPtr}, positional, nil
+}
+
+// repeat runs a command once, or with --watch redraws its output every interval until the context is done.
+// While watching, errors are shown in place of the table so a failed refresh doesn't stop the watch.
+func repeat(ctx context.Context, flags productFlags, output io.Writer, command func(io.Writer) error) error {
+	if !flags.watch {
+		return command(output)
+	}
+
+	for {
+		var table strings.Builder
+		if err := command(&table); err != nil {
+			fmt.Fprintf(&table, "\n%v\n", err)
+		}
+		fmt.Fprintf(output, "%s%s %s/%s, refreshing every %v (Ctrl+C to stop)\n\n%s",
+			clearScreen, time.Now().Format("15:04:05"), flags.store, flags.category, flags.interval, table.String())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(flags.interval):
+		}
+	}
+}
+
+func fetchProducts(ctx context.Context, client *amulclient.Client, flags productFlags) ([]amulclient.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	products, err := client.ListProducts(ctx, flags.category, flags.store)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(products, func(a, b amulclient.Product) int { return strings.Compare(a.SKU, b.SKU) })
+	return products, nil
+}
+
+func listProducts(ctx context.Context, args []string, output io.Writer) error {
+	flags, _, err := parseProductFlags("products list", args)
+	if err != nil {
+		return err
+	}
+	client, err := amulclient.New(shopURL)
+	if err != nil {
+		return err
+	}
+
+	return repeat(ctx, flags, output, func(output io.Writer) error {
+		products, err := fetchProducts(ctx, client, flags)
+		if err != nil {
+			return err
+		}
+		writeProductTable(output, products)
+		return nil
+	})
+}
+
+func checkSKUs(ctx context.Context, args []string, output io.Writer) error {
+	flags, skus, err := parseProductFlags("check", args)
+	if err != nil {
+		return err
+	}
+	if len(skus) == 0 {
+		return fmt.Errorf("check needs at least one SKU\n%s", usage)
+	}
+	client, err := amulclient.New(shopURL)
+	if err != nil {
+		return err
+	}
+
+	return repeat(ctx, flags, output, func(output io.Writer) error {
+		products, err := fetchProducts(ctx, client, flags)
+		if err != nil {
+			return err
+		}
+
+		matching := []amulclient.Product{}
+		missing := []string{}
+		for _, sku := range skus {
+			index := slices.IndexFunc(products, func(product amulclient.Product) bool { return product.SKU == sku })
+			if index < 0 {
+				missing = append(missing, sku)
+				continue
+			}
+			matching = append(matching, products[index])
+		}
+		writeProductTable(output, matching)
+		if len(missing) > 0 {
+			return fmt.Errorf("not listed in %s/%s: %s", flags.store, flags.category, strings.Join(missing, ", "))
+		}
+		return nil
+	})
+}
+
+func resolvePincode(ctx context.Context, args []string, output io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("pincode needs exactly one pincode\n%s", usage)
+	}
+	client, err := amulclient.New(shopURL)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+	substore, err := client.ResolveSubstore(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(output, "%s is served by substore %s\n", substore.Pincode, substore.Substore)
+	return nil
+}
+
+func writeProductTable(output io.Writer, products []amulclient.Product) {
+	table := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "SKU\tAVAILABLE\tQUANTITY\tPRICE\tNAME")
+	for _, product := range products {
+		available := "no"
+		if product.Available == 1 {
+			available = "yes"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%s\n", product.SKU, available, product.InventoryQuantity, product.Price, product.Name)
+	}
+	table.Flush()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// useTestShop points the commands at a fake shop listing two protein products
+func useTestShop(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /en/", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "jsessionid", Value: "session", Path: "/", Expires: time.Now().Add(240 * time.Hour)})
+	})
+	mux.HandleFunc("PUT /entity/ms.settings/_/setPreferences", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("GET /api/1/entity/ms.products", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("filters[0][value][0]") != "protein" {
+			w.Write([]byte(`{"data":[]}`))
+			return
+		}
+		w.Write([]byte(`{"data":[` +
+			`{"sku":"LASCP40_30","name":"Rose Lassi","available":1,"inventory_quantity":12,"price":400},` +
+			`{"sku":"HPPCP01_24","name":"High Protein Paneer","available":0,"inventory_quantity":0,"price":450}]}`))
+	})
+	mux.HandleFunc("GET /entity/pincode", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"records":[{"pincode":"380015","substore":"gujarat-ahmedabad"}]}`))
+	})
+
+	server := httptest.NewServer(mux)
+	shopURL = server.URL
+	t.Cleanup(func() {
+		server.Close()
+		shopURL = ""
+	})
+}
+
+// cancelAfterWrites cancels a context once it has been written to a number of times
+type cancelAfterWrites struct {
+	strings.Builder
+	writes int
+	after  int
+	cancel context.CancelFunc
+}
+
+func (w *cancelAfterWrites) Write(data []byte) (int, error) {
+	w.writes++
+	if w.writes == w.after {
+		w.cancel()
+	}
+	return w.Builder.Write(data)
+}
+
+func TestCommands(t *testing.T) {
+	useTestShop(t)
+	runCommand := func(args ...string) (string, error) {
+		var output strings.Builder
+		err := run(context.Background(), args, &output)
+		return output.String(), err
+	}
+
+	t.Run("List products sorted by SKU", func(t *testing.T) {
+		output, err := runCommand("products", "list", "--store=gujarat")
+		assert.NoError(t, err)
+		assert.Equal(t, "SKU         AVAILABLE  QUANTITY  PRICE  NAME\n"+
+			"HPPCP01_24  no         0         450    High Protein Paneer\n"+
+			"LASCP40_30  yes        12        400    Rose Lassi\n", output)
+	})
+
+	t.Run("Check SKUs with flags before or after them", func(t *testing.T) {
+		output, err := runCommand("check", "LASCP40_30", "--store=gujarat")
+		assert.NoError(t, err)
+		assert.Contains(t, output, "LASCP40_30")
+		assert.NotContains(t, output, "HPPCP01_24")
+
+		output, err = runCommand("check", "--category=protein", "HPPCP01_24", "UNKNOWN")
+		assert.ErrorContains(t, err, "not listed in gujarat/protein: UNKNOWN")
+		assert.Contains(t, output, "HPPCP01_24")
+
+		_, err = runCommand("check")
+		assert.Error(t, err)
+		_, err = runCommand("check", "LASCP40_30", "--interval=0s")
+		assert.Error(t, err)
+	})
+
+	t.Run("Resolve a pincode", func(t *testing.T) {
+		output, err := runCommand("pincode", "380015")
+		assert.NoError(t, err)
+		assert.Equal(t, "380015 is served by substore gujarat-ahmedabad\n", output)
+
+		_, err = runCommand("pincode", "110001")
+		assert.Error(t, err)
+		_, err = runCommand("pincode")
+		assert.Error(t, err)
+	})
+
+	t.Run("Watch redraws until cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		output := &cancelAfterWrites{after: 2, cancel: cancel}
+
+		assert.NoError(t, run(ctx, []string{"products", "list", "--watch", "--interval=1ms"}, output))
+		assert.Equal(t, 2, strings.Count(output.String(), clearScreen))
+		assert.Contains(t, output.String(), "gujarat/protein, refreshing every 1ms")
+		assert.Contains(t, output.String(), "Rose Lassi")
+	})
+
+	t.Run("Reject unknown commands", func(t *testing.T) {
+		_, err := runCommand("products")
+		assert.ErrorContains(t, err, "Usage:")
+		_, err = runCommand("subs")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"amul-notifier/internal/config"
+	"amul-notifier/internal/history"
+	"log"
+	"os"
+)
+
+// runExportHistory writes the requested stock and price history as CSV
+func runExportHistory(args []string) {
+	exportConfig, err := config.ParseExportConfiguration(args)
+	if err != nil {
+		log.Fatalf("Failed to parse export configuration with error[%s]", err.Error())
+	}
+
+	store, err := history.OpenReadOnly(exportConfig.HistoryFile, exportConfig.StoreEncryptionKey)
+	if err != nil {
+		log.Fatalf("Failed to open history file with error[%s]", err.Error())
+	}
+	records := store.Export(exportConfig.SKUs, exportConfig.From, exportConfig.To)
+
+	output := os.Stdout
+	if exportConfig.OutputFile != "" {
+		output, err = os.Create(exportConfig.OutputFile)
+		if err != nil {
+			log.Fatalf("Failed to create output file with error[%s]", err.Error())
+		}
+		defer output.Close()
+	}
+
+	if err := history.WriteCSV(output, records); err != nil {
+		log.Fatalf("Failed to write CSV with error[%s]", err.Error())
+	}
+	log.Printf("Exported %d history records from %s to %s", len(records), exportConfig.From.Format("2006-01-02 15:04"), exportConfig.To.Format("2006-01-02 15:04"))
+}
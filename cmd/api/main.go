@@ -3,32 +3,88 @@ package main
 import (
 	"amul-notifier/internal/bot"
 	"amul-notifier/internal/config"
+	"amul-notifier/internal/outbox"
+	"amul-notifier/internal/tui"
 	"log"
+	"os"
 	"time"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "export-history" {
+		runExportHistory(os.Args[2:])
+		return
+	}
+	runDashboard := len(os.Args) > 1 && os.Args[1] == "tui"
+	if runDashboard {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
 	appConfig, err := config.ParseConfiguration()
 	if err != nil {
 		log.Fatalf("Failed to parse configuration with error[%s]", err.Error())
 	}
 
+	if appConfig.ListDeadLetters {
+		if err := bot.PrintDeadLetters(appConfig); err != nil {
+			log.Fatalf("Failed to read dead letters with error[%s]", err.Error())
+		}
+		return
+	}
+
 	log.Println("Starting Amul product stock notifier...")
 	amulBot, err := bot.InitBot(appConfig)
 	if err != nil {
 		log.Fatalf("Failed to initialize bot with error[%s]", err.Error())
 	}
 
+	bot.StartHTTPServer(amulBot)
 	bot.StartupTestNotification(appConfig)
+	bot.RetryPendingNotifications(amulBot)
 	bot.CheckTargetStock(amulBot)
 	bot.SendInitialStockNotifications(amulBot)
+	bot.SendDailyBriefing(amulBot)
+	bot.SendWeeklyDigest(amulBot)
 
 	bot.SetBotFirstRun(amulBot)
 	log.Printf("Initial setup complete. Regular checks starting with check-interval[%v]", appConfig.CheckInterval)
-	ticker := time.NewTicker(appConfig.CheckInterval)
+	if !runDashboard {
+		runChecks(amulBot, appConfig.CheckInterval, func(tui.StatusUpdate) {})
+		return
+	}
+
+	// The dashboard owns the terminal, so logs go to its log pane
+	logTail := &tui.LogTail{}
+	log.SetOutput(logTail)
+	dashboard := tui.New(logTail)
+	go runChecks(amulBot, appConfig.CheckInterval, func(update tui.StatusUpdate) { dashboard.Send(update) })
+	if _, err := dashboard.Run(); err != nil {
+		log.SetOutput(os.Stderr)
+		log.Fatalf("Dashboard failed with error[%s]", err.Error())
+	}
+}
+
+// runChecks checks stock every interval, reporting the state after the initial check and every later one.
+// Failed notifications are retried in between, on their own shorter ticker.
+func runChecks(amulBot *bot.Bot, interval time.Duration, report func(tui.StatusUpdate)) {
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
+	retryTicker := time.NewTicker(outbox.RetryInterval)
+	defer retryTicker.Stop()
 
-	for range ticker.C {
-		bot.CheckTargetStock(amulBot)
+	checkedAt := time.Now()
+	report(tui.StatusUpdate{Status: bot.CurrentStatus(amulBot), CheckedAt: checkedAt, NextCheckAt: checkedAt.Add(interval)})
+	for {
+		select {
+		case <-retryTicker.C:
+			bot.RetryDueNotifications(amulBot)
+		case checkedAt = <-ticker.C:
+			bot.RetryDueNotifications(amulBot)
+			bot.SendQuietHoursSummary(amulBot)
+			bot.CheckTargetStock(amulBot)
+			bot.SendDailyBriefing(amulBot)
+			bot.SendWeeklyDigest(amulBot)
+			report(tui.StatusUpdate{Status: bot.CurrentStatus(amulBot), CheckedAt: checkedAt, NextCheckAt: checkedAt.Add(interval)})
+		}
 	}
 }
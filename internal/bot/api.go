@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// publishedStatus is the status as of the last check. The check loop publishes it and the HTTP server reads it,
+// so the handlers never touch the bot state while a check is running.
+type publishedStatus struct {
+	mu        sync.RWMutex
+	status    Status
+	checkedAt time.Time
+}
+
+func (p *publishedStatus) publish(status Status, checkedAt time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.status = status
+	p.checkedAt = checkedAt
+}
+
+func (p *publishedStatus) get() (Status, time.Time) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.status, p.checkedAt
+}
+
+type productsResponse struct {
+	Store     string          `json:"store"`
+	CheckedAt time.Time       `json:"checked_at"`
+	Products  []ProductStatus `json:"products"`
+}
+
+type stockResponse struct {
+	Store      string    `json:"store"`
+	CheckedAt  time.Time `json:"checked_at"`
+	InStock    []string  `json:"in_stock"`
+	OutOfStock []string  `json:"out_of_stock"`
+}
+
+// registerAPIRoutes serves the monitored products and their stock as of the last check as JSON
+func registerAPIRoutes(mux *http.ServeMux, bot *Bot) {
+	mux.HandleFunc("GET /products", func(w http.ResponseWriter, r *http.Request) {
+		status, checkedAt := bot.published.get()
+		writeJSON(w, productsResponse{Store: status.Store, CheckedAt: checkedAt, Products: status.Products})
+	})
+
+	mux.HandleFunc("GET /products/{sku}", func(w http.ResponseWriter, r *http.Request) {
+		status, _ := bot.published.get()
+		for _, product := range status.Products {
+			if product.SKU == r.PathValue("sku") {
+				writeJSON(w, product)
+				return
+			}
+		}
+		http.Error(w, "SKU is not monitored", http.StatusNotFound)
+	})
+
+	mux.HandleFunc("GET /stock", func(w http.ResponseWriter, r *http.Request) {
+		status, checkedAt := bot.published.get()
+		response := stockResponse{Store: status.Store, CheckedAt: checkedAt, InStock: []string{}, OutOfStock: []string{}}
+		for _, product := range status.Products {
+			if product.InStock {
+				response.InStock = append(response.InStock, product.SKU)
+			} else {
+				response.OutOfStock = append(response.OutOfStock, product.SKU)
+			}
+		}
+		writeJSON(w, response)
+	})
+}
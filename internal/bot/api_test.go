@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIRoutes(t *testing.T) {
+	checkedAt := time.Date(2025, 5, 1, 9, 30, 0, 0, time.UTC)
+	bot := &Bot{published: &publishedStatus{}}
+	bot.published.publish(Status{Store: "gujarat", Products: []ProductStatus{
+		{SKU: "HPPCP01_24", Name: "High Protein Paneer", InStock: false},
+		{SKU: "LASCP40_30", Name: "Rose Lassi", InStock: true, Quantity: 12, Price: 400},
+	}}, checkedAt)
+
+	mux := http.NewServeMux()
+	registerAPIRoutes(mux, bot)
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+
+	t.Run("List products", func(t *testing.T) {
+		recorder := get("/products")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var response productsResponse
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, "gujarat", response.Store)
+		assert.True(t, checkedAt.Equal(response.CheckedAt))
+		assert.Equal(t, 2, len(response.Products))
+	})
+
+	t.Run("Get one product", func(t *testing.T) {
+		recorder := get("/products/LASCP40_30")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, `{"sku":"LASCP40_30","name":"Rose Lassi","in_stock":true,"quantity":12,"price":400,"possibly_discontinued":false}`,
+			recorder.Body.String())
+
+		assert.Equal(t, http.StatusNotFound, get("/products/UNKNOWN").Code)
+	})
+
+	t.Run("Summarize stock", func(t *testing.T) {
+		var response stockResponse
+		assert.NoError(t, json.Unmarshal(get("/stock").Body.Bytes(), &response))
+		assert.Equal(t, []string{"LASCP40_30"}, response.InStock)
+		assert.Equal(t, []string{"HPPCP01_24"}, response.OutOfStock)
+	})
+}
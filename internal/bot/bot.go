@@ -2,61 +2,121 @@ package bot
 
 import (
 	"amul-notifier/internal/config"
-	"bytes"
-	"encoding/json"
+	"amul-notifier/internal/features"
+	"amul-notifier/internal/gotify"
+	"amul-notifier/internal/history"
+	"amul-notifier/internal/mqtt"
+	"amul-notifier/internal/notion"
+	"amul-notifier/internal/outbox"
+	"amul-notifier/internal/pushover"
+	"amul-notifier/internal/sheets"
+	"amul-notifier/internal/sms"
+	"amul-notifier/internal/statestore"
+	"amul-notifier/internal/webhook"
+	"amul-notifier/internal/whatsapp"
+	"amul-notifier/pkg/amulclient"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
-	"net/http/cookiejar"
-	"strings"
+	"maps"
 	"time"
 )
 
 const (
-	// API URL fetching products in the 'protein' category (adjust filters if needed)
-	apiURL = "https://shop.amul.com/api/1/entity/ms.products?fields[name]=1&fields[brand]=1&fields[categories]=1&fields[collections]=1&fields[alias]=1&fields[sku]=1&fields[price]=1&fields[compare_price]=1&fields[original_price]=1&fields[images]=1&fields[metafields]=1&fields[discounts]=1&fields[catalog_only]=1&fields[is_catalog]=1&fields[seller]=1&fields[available]=1&fields[inventory_quantity]=1&fields[net_quantity]=1&fields[num_reviews]=1&fields[avg_rating]=1&fields[inventory_low_stock_quantity]=1&fields[inventory_allow_out_of_stock]=1&filters[0][field]=categories&filters[0][value][0]=protein&filters[0][operator]=in&facets=true&facetgroup=default_category_facet&limit=100&total=1&start=0"
+	// Amul category holding the monitored products
+	productCategory = "protein"
 
 	productBaseURL = "https://shop.amul.com/en/product/"
 
-	// TODO: configure quiet hours
-	quietHourStart = 0 // 12:00 AM
-	quietHourEnd   = 7 // Up to 6:59:59 AM (exclusive of 7)
-
-	// TODO: parse the expiry time and generate one more cookie again
-	cookieRefreshMargin = 90 * time.Hour // Refresh cookie before it expires
+	// Chats that blocked or removed the bot are skipped for this long before trying again
+	inactiveChatRetryAfter = 24 * time.Hour
 )
 
-// Struct to match the overall JSON response structure
-type ProductListResponse struct {
-	Data []ProductInfo `json:"data"`
-}
-
-// Struct for individual product information within the Data array
-type ProductInfo struct {
-	ID                string `json:"_id"`
-	Name              string `json:"name"`
-	Alias             string `json:"alias"`
-	SKU               string `json:"sku"`
-	Available         int    `json:"available"` // 1 if available, likely 0 otherwise
-	InventoryQuantity int    `json:"inventory_quantity"`
-	Price             int    `json:"price"`
-}
-
 type Bot struct {
 	// SKU -> inStock (bool)
 	productStockState map[string]bool
 
-	// SKU -> ProductInfo
-	productDetails map[string]ProductInfo
+	// SKU -> product details
+	productDetails map[string]amulclient.Product
+
+	// SKU -> onOffer (bool), only tracked when offer alerts are enabled
+	productOfferState map[string]bool
 
 	firstRun bool
 
-	// When the current cookie expires
-	cookieExpiry time.Time
+	// SKU -> below its low-stock threshold (bool), only tracked for SKUs with a threshold
+	lowStockState map[string]bool
+
+	// SKU -> consecutive checks the SKU was missing from the API response
+	missingChecks map[string]int
+
+	// Local date of the last daily briefing, e.g. 2025-05-01
+	lastBriefingDate string
+
+	// Local date of the last weekly digest
+	lastWeeklyDigestDate string
+
+	// Amul shop API client, renews its session by itself
+	amul *amulclient.Client
+
+	// Amul store whose stock is checked, the default store when the configured one was rejected
+	store string
+
+	// Chat ID -> when the chat blocked or removed the bot
+	inactiveChats map[string]time.Time
+
+	// Time spent sending notifications during the current check cycle
+	cycleNotifyDuration time.Duration
+
+	// Latest delivery outcomes, oldest first, for status views
+	recentNotifications []NotificationRecord
+
+	// Per-SKU gauges served on /metrics
+	metrics *stockMetrics
 
-	// Reusable HTTP client with cookie jar
-	httpClient *http.Client
+	// Status as of the last check, served by the REST endpoints
+	published *publishedStatus
+
+	// Stock and price history, nil when no history file is configured
+	history *history.Store
+
+	// Google Sheet receiving every observation, nil when not configured
+	sheets *sheets.Sync
+
+	// Notion database mirroring current stock, nil when not configured
+	notion *notion.Sync
+
+	// Runtime feature flags, nil when no flag file is configured
+	features *features.Flags
+
+	// Record of outgoing notifications, nil when no outbox file is configured
+	outbox *outbox.Outbox
+
+	// MQTT broker receiving stock events, nil when MQTT_BROKER_URL is not set
+	mqtt *mqtt.Client
+
+	// Webhooks receiving stock events, nil when no webhook URL is configured
+	webhooks *webhook.Sender
+
+	// Channels other than Telegram receiving every notification, Telegram chats are added per notification
+	// since chats that blocked the bot are left out for a while
+	notifiers []Notifier
+	// SMS provider for in-stock alerts of appConfig.SMSSKUsMap, nil when not configured
+	sms *sms.Client
+	// SKU/notification type -> when the stock-change alert was last sent, see isDuplicateAlert
+	lastAlerts map[string]time.Time
+	// Alerts suppressed during the current quiet hours, oldest first
+	suppressedAlerts []statestore.SuppressedAlert
+	// Month of smsSent, e.g. 2025-05, and SMS sent to each recipient that month
+	smsMonth string
+	smsSent  int
+
+	// Redis copy of the stock state, nil when REDIS_URL is not set
+	state *statestore.Store
+
+	// Whether the stock state was restored from Redis at startup
+	stateRestored bool
 
 	appConfig *config.AppConfig
 }
@@ -66,84 +126,225 @@ func SetBotFirstRun(bot *Bot) {
 }
 
 func InitBot(appConfig *config.AppConfig) (*Bot, error) {
-	jar, err := cookiejar.New(nil)
+	amulClient, err := amulclient.New("")
 	if err != nil {
 		return nil, err
 	}
 
-	httpClient := &http.Client{
-		Jar: jar,
-	}
-
-	cookieExpiry, err := refreshCookie(httpClient)
+	store, storeWarning, err := selectStore(amulClient, appConfig.Store)
 	if err != nil {
 		return nil, err
 	}
-	return &Bot{
+
+	var notificationOutbox *outbox.Outbox
+	if appConfig.OutboxFile != "" {
+		notificationOutbox, err = outbox.Open(appConfig.OutboxFile, appConfig.StoreEncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var stockHistory *history.Store
+	if appConfig.HistoryFile != "" {
+		stockHistory, err = history.Open(appConfig.HistoryFile, appConfig.StoreEncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var sheetsSync *sheets.Sync
+	if appConfig.SheetsSpreadsheetID != "" {
+		sheetsSync, err = sheets.Open(appConfig.SheetsCredentialsFile, appConfig.SheetsSpreadsheetID, appConfig.SheetsRange)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var featureFlags *features.Flags
+	if appConfig.FeatureFlagsFile != "" {
+		featureFlags, err = features.Open(appConfig.FeatureFlagsFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var notionSync *notion.Sync
+	if appConfig.NotionDatabaseID != "" {
+		notionSync = notion.New(appConfig.NotionToken, appConfig.NotionDatabaseID)
+	}
+
+	var mqttClient *mqtt.Client
+	if appConfig.MQTTBrokerURL != "" {
+		mqttClient, err = mqtt.New(appConfig.MQTTBrokerURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var webhooks *webhook.Sender
+	if len(appConfig.WebhookURLs) > 0 {
+		webhooks = webhook.New(appConfig.WebhookURLs, appConfig.WebhookSecret)
+	}
+
+	notifiers := []Notifier{}
+	if appConfig.PushoverAppToken != "" {
+		notifiers = append(notifiers, &pushoverNotifier{client: pushover.New(appConfig.PushoverAppToken, appConfig.PushoverUserKey)})
+	}
+	if appConfig.GotifyURL != "" {
+		notifiers = append(notifiers, &gotifyNotifier{client: gotify.New(appConfig.GotifyURL, appConfig.GotifyAppToken)})
+	}
+	if appConfig.WhatsAppAccessToken != "" {
+		whatsAppClient := whatsapp.New(appConfig.WhatsAppAccessToken, appConfig.WhatsAppPhoneNumberID)
+		for _, phoneNumber := range appConfig.WhatsAppRecipients {
+			notifiers = append(notifiers, &whatsAppNotifier{client: whatsAppClient, phoneNumber: phoneNumber})
+		}
+	}
+
+	var smsClient *sms.Client
+	switch {
+	case len(appConfig.SMSRecipients) == 0:
+	case appConfig.TwilioAccountSID != "":
+		smsClient = sms.NewTwilio(appConfig.TwilioAccountSID, appConfig.TwilioAuthToken, appConfig.TwilioFromNumber)
+	default:
+		smsClient = sms.NewMSG91(appConfig.MSG91AuthKey, appConfig.MSG91TemplateID)
+	}
+
+	var stateStore *statestore.Store
+	if appConfig.RedisURL != "" {
+		stateStore, err = statestore.Open(appConfig.RedisURL, appConfig.RedisKey, appConfig.StoreEncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bot := &Bot{
 		productStockState: make(map[string]bool),
-		productDetails:    make(map[string]ProductInfo),
-		httpClient:        httpClient,
-		cookieExpiry:      cookieExpiry,
+		productDetails:    make(map[string]amulclient.Product),
+		productOfferState: make(map[string]bool),
+		lowStockState:     make(map[string]bool),
+		missingChecks:     make(map[string]int),
+		lastAlerts:        make(map[string]time.Time),
+		amul:              amulClient,
+		store:             store,
+		inactiveChats:     make(map[string]time.Time),
+		metrics:           newStockMetrics(),
+		published:         &publishedStatus{},
+		history:           stockHistory,
+		sheets:            sheetsSync,
+		notion:            notionSync,
+		features:          featureFlags,
+		outbox:            notificationOutbox,
+		mqtt:              mqttClient,
+		webhooks:          webhooks,
+		notifiers:         notifiers,
+		sms:               smsClient,
+		state:             stateStore,
 		appConfig:         appConfig,
-	}, nil
-}
+	}
+	if err := restoreState(bot); err != nil {
+		return nil, err
+	}
 
-func checkCookie(cookieExpiry time.Time, botHttpClient *http.Client) {
-	if time.Now().Add(cookieRefreshMargin).After(cookieExpiry) {
-		refreshCookie(botHttpClient)
+	if storeWarning != "" {
+		sendNotificationWithRetry(bot, "⚠️ "+escapeHTML(storeWarning)+". Check the --store setting.", "", "startup")
 	}
+	return bot, nil
 }
 
-func CheckTargetStock(bot *Bot) {
-	checkCookie(bot.cookieExpiry, bot.httpClient)
+// selectStore sets the configured store on the session, falling back to the default store when Amul
+// rejects the store code or lists no products for it. The returned warning is empty when no fallback was needed.
+func selectStore(amulClient *amulclient.Client, store string) (string, string, error) {
+	err := verifyStore(amulClient, store)
+	if err == nil {
+		log.Printf("Checking stock for store '%s'", store)
+	}
+	if err == nil || store == config.DefaultStore {
+		return store, "", err
+	}
 
-	log.Printf("Checking stock for %d monitored products...", len(bot.appConfig.MonitoredSKUsMap))
+	warning := fmt.Sprintf("Store '%s' was not accepted by Amul (%v), checking stock for the default store '%s' instead", store, err, config.DefaultStore)
+	log.Printf("Warning: %s", warning)
+	return config.DefaultStore, warning, amulClient.SetPreferences(context.Background(), config.DefaultStore)
+}
 
-	req, err := http.NewRequest("GET", apiURL, nil)
+// verifyStore sets a store on the session and checks that it lists products, which an unknown store code doesn't
+func verifyStore(amulClient *amulclient.Client, store string) error {
+	if err := amulClient.SetPreferences(context.Background(), store); err != nil {
+		return err
+	}
+	products, err := amulClient.ListProducts(context.Background(), productCategory, store)
 	if err != nil {
-		log.Printf("Error creating request: %v", err)
-		return
+		return err
+	}
+	if len(products) == 0 {
+		return errors.New("no products are listed for it")
 	}
+	return nil
+}
 
-	// Set headers
-	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:137.0) Gecko/20100101 Firefox/137.0")
-	req.Header.Set("Referer", "https://shop.amul.com/")
-	req.Header.Set("frontend", "1")
-	req.Header.Set("Connection", "keep-alive")
+func CheckTargetStock(bot *Bot) {
+	log.Printf("Checking stock for %d monitored products and %d products in any pack size...",
+		len(bot.appConfig.MonitoredSKUsMap), len(bot.appConfig.MonitoredVariantsMap))
 
-	resp, err := bot.httpClient.Do(req)
-	if err != nil {
-		log.Printf("Error performing request: %v", err)
-		return
-	}
-	defer resp.Body.Close()
+	timing := newCycleTiming()
+	defer reportCycleTiming(bot, timing)
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("Error reading response body: %v", err)
-		return
+	if err := bot.features.Reload(); err != nil {
+		log.Printf("Error reloading feature flags, keeping the previous ones: %v", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("API returned non-OK status: %s", resp.Status)
+	phaseStart := time.Now()
+	if err := bot.amul.EnsureSession(context.Background(), bot.store); err != nil {
+		log.Printf("Error setting up the Amul session: %v", err)
 		return
 	}
+	timing.add("session", time.Since(phaseStart))
 
-	var productList ProductListResponse
-	err = json.Unmarshal(body, &productList)
+	phaseStart = time.Now()
+	products, err := bot.amul.ListProducts(context.Background(), productCategory, bot.store)
+	timing.add("fetch", time.Since(phaseStart))
 	if err != nil {
-		log.Printf("Error parsing JSON response: %v", err)
+		log.Printf("Error fetching products: %v", err)
 		return
 	}
 
-	log.Printf("Received %d products in API response.", len(productList.Data))
+	log.Printf("Received %d products in API response.", len(products))
 
 	targetSKUsFoundThisCycle := make(map[string]bool)
-
-	for _, product := range productList.Data {
-		if _, isMonitored := bot.appConfig.MonitoredSKUsMap[product.SKU]; isMonitored {
+	checkedAt := time.Now()
+	defer bot.metrics.recordCheck(checkedAt)
+	defer func() { bot.published.publish(CurrentStatus(bot), checkedAt) }()
+	historyRecords := []history.Record{}
+	defer func() {
+		recordStart := time.Now()
+		defer func() { timing.add("record", time.Since(recordStart)) }()
+
+		if err := bot.history.Append(historyRecords); err != nil {
+			log.Printf("Error recording stock history: %v", err)
+		}
+		if err := bot.sheets.AppendRecords(historyRecords); err != nil {
+			log.Printf("Error appending observations to Google Sheets: %v", err)
+		}
+		if err := bot.notion.SyncRows(notionRows(bot, historyRecords), checkedAt); err != nil {
+			log.Printf("Error syncing stock to Notion: %v", err)
+		}
+		saveState(bot)
+	}()
+
+	// Notifications are sent while diffing, their share is measured separately
+	diffStart := time.Now()
+	bot.cycleNotifyDuration = 0
+	defer func() {
+		timing.add("diff", time.Since(diffStart)-bot.cycleNotifyDuration)
+		timing.add("notify", bot.cycleNotifyDuration)
+	}()
+
+	for _, product := range products {
+		if isMonitoredSKU(bot.appConfig, product.SKU) {
+			previousDetails, seenBefore := bot.productDetails[product.SKU]
 			bot.productDetails[product.SKU] = product
 			targetSKUsFoundThisCycle[product.SKU] = true
+			recordListed(bot, product.SKU)
 
 			currentStockStatus := product.Available == 1
 			previousStockStatus, exists := bot.productStockState[product.SKU]
@@ -153,6 +354,20 @@ func CheckTargetStock(bot *Bot) {
 				stockStatusStr = "IN STOCK"
 			}
 			log.Printf("Processing %s (SKU: %s): Status=%s", product.Name, product.SKU, stockStatusStr)
+			bot.metrics.recordObservation(product, currentStockStatus, checkedAt)
+			historyRecords = append(historyRecords, history.Record{
+				At:           checkedAt,
+				SKU:          product.SKU,
+				Available:    currentStockStatus,
+				Quantity:     product.InventoryQuantity,
+				Price:        product.Price,
+				ComparePrice: product.ComparePrice,
+				Store:        bot.store,
+			})
+
+			if !exists || previousStockStatus != currentStockStatus {
+				publishStockChange(bot, product, currentStockStatus, checkedAt)
+			}
 
 			if currentStockStatus {
 				log.Printf("Found IN STOCK: %s (SKU: %s)", product.Name, product.SKU)
@@ -161,26 +376,61 @@ func CheckTargetStock(bot *Bot) {
 					link = fmt.Sprintf("\n\n🔗 <a href=\"%s%s\">View on Amul Shop</a>", productBaseURL, product.Alias)
 				}
 
-				message := fmt.Sprintf("✅ <b>Stock Available!</b>\n\nProduct: <b>%s</b>\nStatus: <b>IN STOCK</b>\nQuantity: %d\nSKU: %s%s",
-					product.Name, product.InventoryQuantity, product.SKU, link)
+				variant := ""
+				if isMonitoredOnlyAsVariant(bot.appConfig, product.SKU) {
+					_, packSize := splitPackSize(product.Name)
+					if packSize == "" {
+						packSize = product.SKU
+					}
+					variant = fmt.Sprintf("\nVariant: <b>%s</b> (watching any pack size of %s)", escapeHTML(packSize), variantGroupKey(product.SKU))
+				}
 
-				sendNotificationWithRetry(bot.appConfig, message, product.SKU, "in-stock")
+				message := fmt.Sprintf("✅ <b>Stock Available!</b>\n\nProduct: <b>%s</b>\nStatus: <b>IN STOCK</b>%s\nQuantity: %d\n%s\nSKU: %s%s%s",
+					productLabel(bot.appConfig, product.Name, product.SKU), variant, product.InventoryQuantity, formatPriceDetails(product), product.SKU,
+					formatSKUNote(bot.appConfig, product.SKU), link)
+				if isCriticalSKU(bot.appConfig, product.SKU) {
+					message = formatUrgencyNote(bot.appConfig, product) + message
+				}
+
+				// The in-stock alert repeats every check, the broadcast channel and paid SMS only get it when the
+				// product comes back
+				if !exists || !previousStockStatus {
+					sendStockChangeNotification(bot, message, product.SKU, "in-stock")
+					sendSMSAlert(bot, product, checkedAt)
+				} else {
+					sendNotificationWithRetry(bot, message, product.SKU, "in-stock")
+				}
 			}
 
 			if !currentStockStatus && exists && previousStockStatus {
 				log.Printf("ℹ️ STOCK UPDATE: %s (SKU: %s) changed to OUT OF STOCK", product.Name, product.SKU)
-				message := fmt.Sprintf("ℹ️ <b>Stock Update</b>\n\nProduct: <b>%s</b>\nStatus: <b>OUT OF STOCK</b>\nSKU: %s",
-					product.Name, product.SKU)
-				sendNotificationWithRetry(bot.appConfig, message, product.SKU, "out-of-stock")
+				message := fmt.Sprintf("ℹ️ <b>Stock Update</b>\n\nProduct: <b>%s</b>\nStatus: <b>OUT OF STOCK</b>\nSKU: %s%s%s",
+					productLabel(bot.appConfig, product.Name, product.SKU), product.SKU,
+					formatSellThrough(bot, product.SKU, checkedAt), formatRestockETA(bot, product.SKU))
+				sendStockChangeNotification(bot, message, product.SKU, "out-of-stock")
 			}
 
 			bot.productStockState[product.SKU] = currentStockStatus
+
+			if bot.features.Enabled(features.OfferAlerts, bot.appConfig.OfferAlerts) {
+				checkOfferStatus(bot, product)
+			}
+			checkLowStock(bot, product)
+			if seenBefore {
+				checkQuantityJump(bot, previousDetails, product)
+			}
 		}
 	}
 
-	for sku := range bot.appConfig.MonitoredSKUsMap {
+	for sku := range trackedSKUs(bot) {
 		if !targetSKUsFoundThisCycle[sku] {
-			if wasInStock, exists := bot.productStockState[sku]; exists && wasInStock {
+			bot.metrics.recordObservation(amulclient.Product{SKU: sku}, false, checkedAt)
+			historyRecords = append(historyRecords, history.Record{At: checkedAt, SKU: sku, Available: false, Store: bot.store})
+			wasInStock, exists := bot.productStockState[sku]
+			if !exists || wasInStock {
+				publishStockChange(bot, amulclient.Product{SKU: sku, Name: bot.productDetails[sku].Name}, false, checkedAt)
+			}
+			if exists && wasInStock {
 				log.Printf("WARNING: Monitored SKU %s was NOT found in API response. Assuming OUT OF STOCK.", sku)
 				bot.productStockState[sku] = false
 
@@ -190,8 +440,9 @@ func CheckTargetStock(bot *Bot) {
 					name = prodInfo.Name
 				}
 
-				message := fmt.Sprintf("<b>Stock Update (Not Found)</b>\n\nProduct: <b>%s</b>\nStatus: <b>Assumed OUT OF STOCK</b> (Not in API response)\nSKU: %s", name, sku)
-				sendNotificationWithRetry(bot.appConfig, message, sku, "assumed-out-of-stock")
+				message := fmt.Sprintf("<b>Stock Update (Not Found)</b>\n\nProduct: <b>%s</b>\nStatus: <b>Assumed OUT OF STOCK</b> (Not in API response)\nSKU: %s%s%s", productLabel(bot.appConfig, name, sku), sku,
+					formatSellThrough(bot, sku, checkedAt), formatRestockETA(bot, sku))
+				sendStockChangeNotification(bot, message, sku, "assumed-out-of-stock")
 			} else if !exists {
 				log.Printf("INFO: Monitored SKU %s was not found in API response and was not previously tracked. Marking as OUT OF STOCK.", sku)
 				bot.productStockState[sku] = false
@@ -199,93 +450,126 @@ func CheckTargetStock(bot *Bot) {
 				log.Printf("INFO: Monitored SKU %s was not found in API response (was already recorded as out of stock).", sku)
 				bot.productStockState[sku] = false
 			}
+			if recordMissing(bot, sku) {
+				sendDiscontinuedAlert(bot, sku)
+			}
 		}
 	}
 }
 
-func refreshCookie(httpClient *http.Client) (time.Time, error) {
-	log.Println("Refreshing Amul API cookie...")
+// restoreState loads the state saved by a previous run, so a restart doesn't repeat alerts already sent
+func restoreState(bot *Bot) error {
+	state, found, err := bot.state.Load(context.Background())
+	if err != nil || !found {
+		return err
+	}
 
-	var cookieExpiry time.Time
+	maps.Copy(bot.productStockState, state.StockState)
+	maps.Copy(bot.productOfferState, state.OfferState)
+	maps.Copy(bot.lowStockState, state.LowStockState)
+	maps.Copy(bot.inactiveChats, state.InactiveChats)
+	maps.Copy(bot.missingChecks, state.MissingChecks)
+	maps.Copy(bot.lastAlerts, state.LastAlerts)
+	bot.suppressedAlerts = state.SuppressedAlerts
+	bot.lastBriefingDate = state.LastBriefingDate
+	bot.lastWeeklyDigestDate = state.LastWeeklyDigestDate
+	bot.smsMonth, bot.smsSent = state.SMSMonth, state.SMSSent
+	bot.stateRestored = true
+	log.Printf("Restored stock state of %d SKUs saved at %s", len(state.StockState), state.SavedAt.Format(time.RFC3339))
+	return nil
+}
 
-	// First request to get the jsessionid
-	targetURL := "https://shop.amul.com/en/"
-	req, err := http.NewRequest("GET", targetURL, nil)
+// saveState writes the current state to Redis, a failed write is retried with the next save
+func saveState(bot *Bot) {
+	err := bot.state.Save(context.Background(), statestore.State{
+		StockState:           bot.productStockState,
+		OfferState:           bot.productOfferState,
+		LowStockState:        bot.lowStockState,
+		LastBriefingDate:     bot.lastBriefingDate,
+		LastWeeklyDigestDate: bot.lastWeeklyDigestDate,
+		InactiveChats:        bot.inactiveChats,
+		MissingChecks:        bot.missingChecks,
+		LastAlerts:           bot.lastAlerts,
+		SuppressedAlerts:     bot.suppressedAlerts,
+		SMSMonth:             bot.smsMonth,
+		SMSSent:              bot.smsSent,
+	})
 	if err != nil {
-		return cookieExpiry, err
+		log.Printf("Error saving stock state: %v", err)
 	}
+}
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/58.0.3029.110 Safari/537.3")
-
-	resp, err := httpClient.Do(req)
-	if err != nil {
-		return cookieExpiry, err
-	}
-	defer resp.Body.Close()
-
-	// Extract expiration from headers
-	for _, headerCookieStr := range resp.Header["Set-Cookie"] {
-		parts := strings.Split(headerCookieStr, ";")
-		if len(parts) > 0 && strings.HasPrefix(strings.TrimSpace(parts[0]), "jsessionid=") {
-			for _, part := range parts {
-				trimmedPart := strings.TrimSpace(part)
-				if strings.HasPrefix(trimmedPart, "Expires=") {
-					expiresStr := strings.TrimPrefix(trimmedPart, "Expires=")
-					expiry, err := time.Parse(time.RFC1123, expiresStr)
-					if err != nil {
-						log.Printf("Warning: Could not parse cookie expiry time: %v", err)
-						cookieExpiry = time.Now().Add(24 * time.Hour)
-					} else {
-						cookieExpiry = expiry
-						log.Printf("Cookie expires at: %v", cookieExpiry)
-					}
-					break
-				}
-			}
-			break
+// notionRows turns the observations of a check into Notion rows, named after the product when it is known
+func notionRows(bot *Bot, records []history.Record) []notion.Row {
+	rows := make([]notion.Row, 0, len(records))
+	for _, record := range records {
+		name := record.SKU
+		if product, exists := bot.productDetails[record.SKU]; exists {
+			name = product.Name
 		}
+		rows = append(rows, notion.Row{SKU: record.SKU, Name: name, InStock: record.Available, Quantity: record.Quantity, Price: record.Price})
 	}
+	return rows
+}
+
+// isMonitoredSKU reports whether a SKU is monitored directly or through one of its variant groups
+func isMonitoredSKU(appConfig *config.AppConfig, sku string) bool {
+	return appConfig.MonitoredSKUsMap[sku] || appConfig.MonitoredVariantsMap[variantGroupKey(sku)]
+}
 
-	// Now validate the cookie
-	putURL := "https://shop.amul.com/entity/ms.settings/_/setPreferences"
-	payload := map[string]map[string]string{
-		"data": {
-			"store": "gujarat",
-		},
+func isMonitoredOnlyAsVariant(appConfig *config.AppConfig, sku string) bool {
+	return !appConfig.MonitoredSKUsMap[sku] && appConfig.MonitoredVariantsMap[variantGroupKey(sku)]
+}
+
+// isSMSSKU reports whether a SKU, or its variant group, has opted in to SMS alerts
+func isSMSSKU(appConfig *config.AppConfig, sku string) bool {
+	return appConfig.SMSSKUsMap[sku] || appConfig.SMSSKUsMap[variantGroupKey(sku)+"_*"]
+}
+
+// isCriticalSKU reports whether a SKU, or its variant group, is marked critical
+func isCriticalSKU(appConfig *config.AppConfig, sku string) bool {
+	return appConfig.CriticalSKUsMap[sku] || appConfig.CriticalSKUsMap[variantGroupKey(sku)+"_*"]
+}
+
+// trackedSKUs returns the configured SKUs along with every variant SKU seen so far
+func trackedSKUs(bot *Bot) map[string]bool {
+	skus := make(map[string]bool, len(bot.appConfig.MonitoredSKUsMap)+len(bot.productStockState))
+	for sku := range bot.appConfig.MonitoredSKUsMap {
+		skus[sku] = true
 	}
-	jsonPayload, _ := json.Marshal(payload)
+	for sku := range bot.productStockState {
+		skus[sku] = true
+	}
+	return skus
+}
 
-	req, err = http.NewRequest("PUT", putURL, bytes.NewBuffer(jsonPayload))
-	if err != nil {
-		return cookieExpiry, err
-	}
-
-	// Set all required headers
-	req.Header.Set("accept", "application/json, text/plain, */*")
-	req.Header.Set("accept-language", "en-US,en;q=0.9")
-	req.Header.Set("content-type", "application/json")
-	req.Header.Set("frontend", "1")
-	req.Header.Set("origin", "https://shop.amul.com")
-	req.Header.Set("priority", "u=1, i")
-	req.Header.Set("referer", "https://shop.amul.com/")
-	req.Header.Set("sec-ch-ua", `"Chromium";v="135", "Not-A.Brand";v="8"`)
-	req.Header.Set("sec-ch-ua-mobile", "?0")
-	req.Header.Set("sec-ch-ua-platform", `"Linux"`)
-	req.Header.Set("sec-fetch-dest", "empty")
-	req.Header.Set("sec-fetch-mode", "cors")
-	req.Header.Set("sec-fetch-site", "same-origin")
-	req.Header.Set("user-agent", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/135.0.0.0 Safari/537.36")
-
-	resp, err = httpClient.Do(req)
-	if err != nil {
-		return cookieExpiry, err
+// checkOfferStatus alerts once when a discount appears on a product, and resets when the discount ends
+func checkOfferStatus(bot *Bot, product amulclient.Product) {
+	currentOfferStatus := product.ComparePrice > product.Price && product.Price > 0
+	previousOfferStatus := bot.productOfferState[product.SKU]
+	bot.productOfferState[product.SKU] = currentOfferStatus
+
+	if !currentOfferStatus {
+		if previousOfferStatus {
+			log.Printf("Offer ended for %s (SKU: %s)", product.Name, product.SKU)
+		}
+		return
+	}
+	if previousOfferStatus {
+		return
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		log.Fatalf("Cookie validation failed with status: %d", resp.StatusCode)
+	log.Printf("Found ON OFFER: %s (SKU: %s) at %d%% off", product.Name, product.SKU, discountPercent(product.Price, product.ComparePrice))
+	stockStatusStr := "OUT OF STOCK"
+	if product.Available == 1 {
+		stockStatusStr = "IN STOCK"
+	}
+	link := ""
+	if product.Alias != "" {
+		link = fmt.Sprintf("\n\n🔗 <a href=\"%s%s\">View on Amul Shop</a>", productBaseURL, product.Alias)
 	}
 
-	log.Println("Cookie successfully refreshed and validated")
-	return cookieExpiry, nil
+	message := fmt.Sprintf("🏷️ <b>On Offer!</b>\n\nProduct: <b>%s</b>\n%s\nStatus: <b>%s</b>\nSKU: %s%s",
+		productLabel(bot.appConfig, product.Name, product.SKU), formatPriceDetails(product), stockStatusStr, product.SKU, link)
+	sendNotificationWithRetry(bot, message, product.SKU, "on-offer")
 }
@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"amul-notifier/internal/config"
+	"amul-notifier/pkg/amulclient"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOfferAlerts(t *testing.T) {
+	t.Run("Alert once when an offer starts and again after it ended", func(t *testing.T) {
+		pushover := &fakeNotifier{channel: "pushover"}
+		bot := &Bot{
+			productOfferState: map[string]bool{},
+			// The primary chat is marked inactive so alerts only reach the fake notifier
+			inactiveChats: map[string]time.Time{"": time.Now()},
+			notifiers:     []Notifier{pushover},
+			appConfig:     &config.AppConfig{},
+		}
+		product := func(price, comparePrice int) amulclient.Product {
+			return amulclient.Product{SKU: "LASCP40_30", Name: "Rose Lassi", Available: 1, Price: price, ComparePrice: comparePrice}
+		}
+
+		checkOfferStatus(bot, product(450, 450))
+		assert.Empty(t, pushover.sent)
+
+		checkOfferStatus(bot, product(400, 450))
+		assert.Len(t, pushover.sent, 1)
+		assert.Equal(t, "on-offer", pushover.sent[0].Type)
+		assert.Contains(t, pushover.sent[0].Message, "11% off")
+		assert.True(t, bot.productOfferState["LASCP40_30"])
+
+		// No repeat while the offer continues, even when the discount changes
+		checkOfferStatus(bot, product(400, 450))
+		checkOfferStatus(bot, product(380, 450))
+		assert.Len(t, pushover.sent, 1)
+
+		checkOfferStatus(bot, product(450, 450))
+		assert.False(t, bot.productOfferState["LASCP40_30"])
+		assert.Len(t, pushover.sent, 1)
+
+		checkOfferStatus(bot, product(420, 450))
+		assert.Len(t, pushover.sent, 2)
+	})
+
+	t.Run("Products without a price are never on offer", func(t *testing.T) {
+		pushover := &fakeNotifier{channel: "pushover"}
+		bot := &Bot{productOfferState: map[string]bool{}, inactiveChats: map[string]time.Time{"": time.Now()}, notifiers: []Notifier{pushover}, appConfig: &config.AppConfig{}}
+
+		checkOfferStatus(bot, amulclient.Product{SKU: "LASCP40_30", ComparePrice: 450})
+		assert.Empty(t, pushover.sent)
+	})
+}
+
+// newTestShopBot returns a bot checking a fake shop that lists the given products, with the primary Telegram
+// chat marked inactive so alerts only reach the bot's other notifiers
+func newTestShopBot(t *testing.T, appConfig *config.AppConfig, products *string) *Bot {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /en/", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "jsessionid", Value: "session", Path: "/", Expires: time.Now().Add(240 * time.Hour)})
+	})
+	mux.HandleFunc("PUT /entity/ms.settings/_/setPreferences", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("GET /api/1/entity/ms.products", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[` + *products + `]}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	amulClient, err := amulclient.New(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Bot{
+		productStockState: make(map[string]bool),
+		productDetails:    make(map[string]amulclient.Product),
+		productOfferState: make(map[string]bool),
+		lowStockState:     make(map[string]bool),
+		missingChecks:     make(map[string]int),
+		lastAlerts:        make(map[string]time.Time),
+		inactiveChats:     map[string]time.Time{"": time.Now()},
+		amul:              amulClient,
+		store:             "gujarat",
+		metrics:           newStockMetrics(),
+		published:         &publishedStatus{},
+		appConfig:         appConfig,
+	}
+}
+
+// roundTripFunc answers HTTP requests in tests
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
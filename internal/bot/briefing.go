@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"amul-notifier/internal/features"
+	"amul-notifier/internal/history"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// How far back the briefing looks for stock and price changes
+const briefingLookback = 24 * time.Hour
+
+// A stock status change found in the history
+type stockChange struct {
+	at      time.Time
+	inStock bool
+}
+
+// stockChanges returns every change of availability between consecutive records of one SKU
+func stockChanges(records []history.Record) []stockChange {
+	changes := []stockChange{}
+	for i := 1; i < len(records); i++ {
+		if records[i].Available != records[i-1].Available {
+			changes = append(changes, stockChange{at: records[i].At, inStock: records[i].Available})
+		}
+	}
+	return changes
+}
+
+// SendDailyBriefing sends the daily briefing with the first check at or after the configured briefing time
+func SendDailyBriefing(bot *Bot) {
+	if bot.appConfig.BriefingTime == "" || !bot.features.Enabled(features.DailyBriefing, true) {
+		return
+	}
+
+	now := time.Now()
+	if bot.appConfig.Timezone != nil {
+		now = now.In(bot.appConfig.Timezone)
+	}
+	today := now.Format(time.DateOnly)
+	if bot.lastBriefingDate == today || now.Format("15:04") < bot.appConfig.BriefingTime {
+		return
+	}
+	if isQuietHours(bot.appConfig) {
+		log.Printf("Daily briefing postponed due to quiet hours.")
+		return
+	}
+
+	bot.lastBriefingDate = today
+	saveState(bot)
+	sendNotificationWithRetry(bot, buildDailyBriefing(bot, now), "", "briefing")
+}
+
+// buildDailyBriefing summarizes current stock and, with a history file, the stock and price changes of the last day
+func buildDailyBriefing(bot *Bot, now time.Time) string {
+	skus := []string{}
+	for sku := range trackedSKUs(bot) {
+		skus = append(skus, sku)
+	}
+	slices.Sort(skus)
+
+	var inStock, outOfStock, changes, priceChanges []string
+	for _, sku := range skus {
+		name := sku
+		if product, exists := bot.productDetails[sku]; exists {
+			name = product.Name
+		}
+		label := productLabel(bot.appConfig, name, sku)
+
+		if bot.productStockState[sku] {
+			product := bot.productDetails[sku]
+			inStock = append(inStock, fmt.Sprintf("✅ %s, %s, %d left", label, formatINR(product.Price), product.InventoryQuantity))
+		} else {
+			outOfStock = append(outOfStock, "❌ "+label)
+		}
+
+		records := bot.history.Query(sku, now.Add(-briefingLookback), now)
+		for _, change := range stockChanges(records) {
+			marker, status := "•", "went OUT OF STOCK"
+			if isQuietTime(bot.appConfig, change.at.In(now.Location())) {
+				marker = "🌙"
+			}
+			if change.inStock {
+				status = "came back IN STOCK"
+			}
+			changes = append(changes, fmt.Sprintf("%s %s %s %s", marker, change.at.In(now.Location()).Format("15:04"), label, status))
+		}
+		if priceChange := formatPriceChange(label, records); priceChange != "" {
+			priceChanges = append(priceChanges, priceChange)
+		}
+	}
+
+	var briefing strings.Builder
+	briefing.WriteString(fmt.Sprintf("☀️ <b>Daily Briefing</b> (%s)\n", now.Format("Mon 2 Jan")))
+	writeBriefingSection(&briefing, "In stock now", inStock)
+	writeBriefingSection(&briefing, "Out of stock", outOfStock)
+	if bot.history != nil {
+		if len(changes) == 0 {
+			changes = []string{"No stock changes"}
+		} else {
+			changes = append(changes, "<i>🌙 marks changes during quiet hours</i>")
+		}
+		writeBriefingSection(&briefing, "Last 24 hours", changes)
+		writeBriefingSection(&briefing, "Price changes", priceChanges)
+	}
+	return briefing.String()
+}
+
+// formatPriceChange describes the change between the first and last known price of a SKU, empty when it didn't change
+func formatPriceChange(label string, records []history.Record) string {
+	if len(records) < 2 {
+		return ""
+	}
+	firstPrice, lastPrice := records[0].Price, records[len(records)-1].Price
+	if firstPrice == 0 || lastPrice == 0 || firstPrice == lastPrice {
+		return ""
+	}
+	return fmt.Sprintf("💰 %s: %s → %s", label, formatINR(firstPrice), formatINR(lastPrice))
+}
+
+// writeBriefingSection appends a titled list, skipping empty sections
+func writeBriefingSection(briefing *strings.Builder, title string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	briefing.WriteString(fmt.Sprintf("\n<b>%s</b>\n%s\n", title, strings.Join(lines, "\n")))
+}
@@ -0,0 +1,40 @@
+package bot
+
+import (
+	"amul-notifier/internal/config"
+	"amul-notifier/internal/history"
+	"amul-notifier/pkg/amulclient"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDailyBriefing(t *testing.T) {
+	t.Run("Summarize stock, overnight changes and prices", func(t *testing.T) {
+		store, err := history.Open(filepath.Join(t.TempDir(), "history.jsonl"), nil)
+		assert.NoError(t, err)
+		now := time.Date(2025, 5, 2, 8, 0, 0, 0, time.UTC)
+		assert.NoError(t, store.Append([]history.Record{
+			{At: now.Add(-10 * time.Hour), SKU: "LASCP40_30", Available: false, Price: 450},
+			{At: now.Add(-5 * time.Hour), SKU: "LASCP40_30", Available: true, Price: 400},
+			{At: now.Add(-5 * time.Hour), SKU: "HPPCP01_24", Available: false},
+		}))
+
+		bot := &Bot{
+			productStockState: map[string]bool{"LASCP40_30": true, "HPPCP01_24": false},
+			productDetails: map[string]amulclient.Product{
+				"LASCP40_30": {SKU: "LASCP40_30", Name: "Rose Lassi", Price: 400, InventoryQuantity: 12},
+			},
+			history:   store,
+			appConfig: &config.AppConfig{MonitoredSKUsMap: map[string]bool{"LASCP40_30": true, "HPPCP01_24": true}, QuietHoursEnd: 7 * 60},
+		}
+
+		assert.Equal(t, "☀️ <b>Daily Briefing</b> (Fri 2 May)\n"+
+			"\n<b>In stock now</b>\n✅ Rose Lassi, ₹400, 12 left\n"+
+			"\n<b>Out of stock</b>\n❌ HPPCP01_24\n"+
+			"\n<b>Last 24 hours</b>\n🌙 03:00 Rose Lassi came back IN STOCK\n<i>🌙 marks changes during quiet hours</i>\n"+
+			"\n<b>Price changes</b>\n💰 Rose Lassi: ₹450 → ₹400\n", buildDailyBriefing(bot, now))
+	})
+}
@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"amul-notifier/internal/gotify"
+	"amul-notifier/internal/pushover"
+	"amul-notifier/internal/whatsapp"
+	"amul-notifier/pkg/amulclient"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Title of notifications on channels that show one
+const notificationTitle = "Amul Stock Notifier"
+
+// pushoverPriority maps delivery options to a Pushover priority: critical restocks bypass the phone's quiet hours
+// and silent notification types arrive without sound
+func pushoverPriority(options messageOptions) int {
+	switch {
+	case options.pin:
+		return pushover.PriorityHigh
+	case options.silent:
+		return pushover.PriorityQuiet
+	default:
+		return pushover.PriorityNormal
+	}
+}
+
+// pushoverNotifier delivers to a Pushover user or group
+type pushoverNotifier struct {
+	client *pushover.Client
+}
+
+func (n *pushoverNotifier) Channel() string   { return "pushover" }
+func (n *pushoverNotifier) Recipient() string { return "" }
+
+func (n *pushoverNotifier) Send(notification Notification) error {
+	return n.client.Send(notificationTitle, notification.Message, pushoverPriority(notification.options))
+}
+
+// gotifyPriority maps delivery options to a Gotify priority, following the same rules as pushoverPriority
+func gotifyPriority(options messageOptions) int {
+	switch {
+	case options.pin:
+		return gotify.PriorityHigh
+	case options.silent:
+		return gotify.PriorityQuiet
+	default:
+		return gotify.PriorityNormal
+	}
+}
+
+// gotifyNotifier delivers to a Gotify application as Markdown
+type gotifyNotifier struct {
+	client *gotify.Client
+}
+
+func (n *gotifyNotifier) Channel() string   { return "gotify" }
+func (n *gotifyNotifier) Recipient() string { return "" }
+
+func (n *gotifyNotifier) Send(notification Notification) error {
+	return n.client.Send(notificationTitle, htmlToMarkdown(notification.Message), gotifyPriority(notification.options))
+}
+
+// whatsAppNotifier delivers to one WhatsApp phone number
+type whatsAppNotifier struct {
+	client      *whatsapp.Client
+	phoneNumber string
+}
+
+func (n *whatsAppNotifier) Channel() string   { return "whatsapp" }
+func (n *whatsAppNotifier) Recipient() string { return n.phoneNumber }
+
+func (n *whatsAppNotifier) Send(notification Notification) error {
+	return n.client.Send(n.phoneNumber, htmlToWhatsApp(notification.Message))
+}
+
+// smsText is a short plain text in-stock alert. It stays in the GSM alphabet, so no emoji or rupee sign, to fit in
+// as few SMS parts as possible.
+func smsText(product amulclient.Product) string {
+	text := fmt.Sprintf("Amul: %s is IN STOCK, %d left.", product.Name, product.InventoryQuantity)
+	if product.Alias != "" {
+		text += " " + productBaseURL + product.Alias
+	}
+	return text
+}
+
+// sendSMSAlert sends an in-stock alert by SMS for opted-in SKUs, until the monthly limit is reached. A SKU
+// flapping back in stock within the dedupe window isn't texted again. Failed SMS aren't retried, a request that
+// timed out may still have been delivered and billed.
+func sendSMSAlert(bot *Bot, product amulclient.Product, now time.Time) {
+	if bot.sms == nil || !isSMSSKU(bot.appConfig, product.SKU) || isPaused(bot.appConfig, now) ||
+		(isQuietHours(bot.appConfig) && !bypassesQuietHours(bot.appConfig, product.SKU, "in-stock")) {
+		return
+	}
+
+	if bot.appConfig.Timezone != nil {
+		now = now.In(bot.appConfig.Timezone)
+	}
+	if month := now.Format("2006-01"); bot.smsMonth != month {
+		bot.smsMonth, bot.smsSent = month, 0
+	}
+	if bot.smsSent >= bot.appConfig.SMSMonthlyLimit {
+		log.Printf("SMS alert for %s skipped, the monthly limit of %d is reached.", product.SKU, bot.appConfig.SMSMonthlyLimit)
+		return
+	}
+	if isDuplicate(bot, dedupeKey(product.SKU, "sms"), now) {
+		log.Printf("SMS alert for %s skipped, one was sent within the last %v.", product.SKU, bot.appConfig.DedupeWindow)
+		return
+	}
+
+	bot.smsSent++
+	saveState(bot)
+	text := smsText(product)
+	for _, recipient := range bot.appConfig.SMSRecipients {
+		err := bot.sms.Send(recipient, text)
+		if err != nil {
+			log.Printf("FAILED to send SMS alert for %s to %s: %v", product.SKU, recipient, err)
+		} else {
+			log.Printf("SMS alert sent successfully for %s to %s (%d/%d this month).", product.SKU, recipient, bot.smsSent, bot.appConfig.SMSMonthlyLimit)
+		}
+		recordNotification(bot, "in-stock", product.SKU, "sms:"+recipient, err)
+	}
+}
@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"amul-notifier/internal/config"
+	"amul-notifier/internal/pushover"
+	"amul-notifier/internal/sms"
+	"amul-notifier/pkg/amulclient"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPushoverPriority(t *testing.T) {
+	t.Run("Map delivery options to priorities", func(t *testing.T) {
+		assert.Equal(t, pushover.PriorityHigh, pushoverPriority(messageOptions{pin: true}))
+		assert.Equal(t, pushover.PriorityQuiet, pushoverPriority(messageOptions{silent: true}))
+		assert.Equal(t, pushover.PriorityNormal, pushoverPriority(messageOptions{}))
+	})
+
+	t.Run("Escalate a critical restock once, not with every in-stock check", func(t *testing.T) {
+		products := `{"sku":"WPCCP03_01","name":"Whey Protein","available":1,"inventory_quantity":4,"price":2000}`
+		bot := newTestShopBot(t, &config.AppConfig{
+			MonitoredSKUsMap: map[string]bool{"WPCCP03_01": true},
+			CriticalSKUsMap:  map[string]bool{"WPCCP03_01": true},
+			SilentAlerts:     map[string]bool{"all": true},
+		}, &products)
+		notifier := &fakeNotifier{channel: "pushover"}
+		bot.notifiers = []Notifier{notifier}
+
+		CheckTargetStock(bot)
+		CheckTargetStock(bot)
+		if assert.Len(t, notifier.sent, 2) {
+			assert.True(t, notifier.sent[0].options.pin)
+			assert.Equal(t, pushover.PriorityHigh, pushoverPriority(notifier.sent[0].options))
+			assert.False(t, notifier.sent[1].options.pin)
+			assert.Equal(t, pushover.PriorityNormal, pushoverPriority(notifier.sent[1].options))
+		}
+	})
+}
+
+func TestSMSAlerts(t *testing.T) {
+	t.Run("Opt in SKUs and variant groups", func(t *testing.T) {
+		appConfig := &config.AppConfig{SMSSKUsMap: map[string]bool{"WPCCP03_01": true, "HPPCP01_*": true}}
+		assert.True(t, isSMSSKU(appConfig, "WPCCP03_01"))
+		assert.True(t, isSMSSKU(appConfig, "HPPCP01_02"))
+		assert.False(t, isSMSSKU(appConfig, "LASCP40_30"))
+	})
+
+	t.Run("Write a short plain text alert", func(t *testing.T) {
+		product := amulclient.Product{Name: "Amul High Protein Rose Lassi", InventoryQuantity: 12, Alias: "amul-rose-lassi"}
+		assert.Equal(t, "Amul: Amul High Protein Rose Lassi is IN STOCK, 12 left. https://shop.amul.com/en/product/amul-rose-lassi", smsText(product))
+	})
+
+	t.Run("Text once per restock, not with every in-stock check", func(t *testing.T) {
+		// Requests to Twilio are answered here, everything else goes to the fake shop
+		var texts atomic.Int32
+		defaultTransport := http.DefaultTransport
+		http.DefaultTransport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Host != "api.twilio.com" {
+				return defaultTransport.RoundTrip(req)
+			}
+			texts.Add(1)
+			return &http.Response{StatusCode: http.StatusCreated, Body: io.NopCloser(strings.NewReader("{}")), Request: req}, nil
+		})
+		defer func() { http.DefaultTransport = defaultTransport }()
+
+		products := `{"sku":"LASCP40_30","name":"Rose Lassi","available":1,"inventory_quantity":12,"price":400}`
+		bot := newTestShopBot(t, &config.AppConfig{
+			MonitoredSKUsMap: map[string]bool{"LASCP40_30": true},
+			SMSSKUsMap:       map[string]bool{"LASCP40_30": true},
+			SMSRecipients:    []string{"919876543210"},
+			SMSMonthlyLimit:  20,
+		}, &products)
+		bot.sms = sms.NewTwilio("AC123", "token", "+14155550123")
+
+		CheckTargetStock(bot)
+		CheckTargetStock(bot)
+		assert.Equal(t, int32(1), texts.Load())
+		assert.Equal(t, 1, bot.smsSent)
+
+		outOfStock := `{"sku":"LASCP40_30","name":"Rose Lassi","available":0}`
+		inStock := `{"sku":"LASCP40_30","name":"Rose Lassi","available":1,"inventory_quantity":3,"price":400}`
+		products = outOfStock
+		CheckTargetStock(bot)
+		products = inStock
+		CheckTargetStock(bot)
+		assert.Equal(t, int32(2), texts.Load())
+
+		// Flapping out of stock and back within the dedupe window doesn't spend another SMS
+		bot.appConfig.DedupeWindow = time.Hour
+		bot.lastAlerts[dedupeKey("LASCP40_30", "sms")] = time.Now()
+		products = outOfStock
+		CheckTargetStock(bot)
+		products = inStock
+		CheckTargetStock(bot)
+		assert.Equal(t, int32(2), texts.Load())
+		assert.Equal(t, 2, bot.smsSent)
+	})
+}
@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"maps"
+	"time"
+)
+
+// dedupeKey identifies an alert by SKU and the stock state it reports
+func dedupeKey(sku, notificationType string) string {
+	return sku + "/" + notificationType
+}
+
+// isDuplicateAlert reports whether the same stock-change alert for a SKU went out within the dedupe window,
+// recording the alert otherwise. Alerts that fell out of the window are forgotten.
+func isDuplicateAlert(bot *Bot, sku, notificationType string, now time.Time) bool {
+	if !stockChangeTypes[notificationType] {
+		return false
+	}
+	return isDuplicate(bot, dedupeKey(sku, notificationType), now)
+}
+
+// isDuplicate reports whether an alert with the given key went out within the dedupe window, recording it otherwise
+func isDuplicate(bot *Bot, key string, now time.Time) bool {
+	window := bot.appConfig.DedupeWindow
+	if window <= 0 {
+		return false
+	}
+
+	maps.DeleteFunc(bot.lastAlerts, func(_ string, sentAt time.Time) bool {
+		return now.Sub(sentAt) >= window
+	})
+	if _, sentRecently := bot.lastAlerts[key]; sentRecently {
+		return true
+	}
+	bot.lastAlerts[key] = now
+	return false
+}
@@ -0,0 +1,33 @@
+package bot
+
+import (
+	"amul-notifier/internal/config"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicateAlerts(t *testing.T) {
+	t.Run("Suppress repeated stock changes within the window", func(t *testing.T) {
+		bot := &Bot{lastAlerts: map[string]time.Time{}, appConfig: &config.AppConfig{DedupeWindow: 30 * time.Minute}}
+		start := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+
+		// The API flaps in, out and back in stock within minutes
+		assert.False(t, isDuplicateAlert(bot, "LASCP40_30", "in-stock", start))
+		assert.False(t, isDuplicateAlert(bot, "LASCP40_30", "out-of-stock", start.Add(5*time.Minute)))
+		assert.True(t, isDuplicateAlert(bot, "LASCP40_30", "in-stock", start.Add(10*time.Minute)))
+		assert.False(t, isDuplicateAlert(bot, "LASCP61_30", "in-stock", start.Add(10*time.Minute)))
+
+		assert.False(t, isDuplicateAlert(bot, "LASCP40_30", "in-stock", start.Add(30*time.Minute)))
+		assert.False(t, isDuplicateAlert(bot, "LASCP40_30", "briefing", start.Add(31*time.Minute)))
+		assert.False(t, isDuplicateAlert(bot, "LASCP40_30", "briefing", start.Add(32*time.Minute)))
+	})
+
+	t.Run("Allow everything without a window", func(t *testing.T) {
+		bot := &Bot{lastAlerts: map[string]time.Time{}, appConfig: &config.AppConfig{}}
+		now := time.Now()
+		assert.False(t, isDuplicateAlert(bot, "LASCP40_30", "in-stock", now))
+		assert.False(t, isDuplicateAlert(bot, "LASCP40_30", "in-stock", now))
+	})
+}
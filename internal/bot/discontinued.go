@@ -0,0 +1,39 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+)
+
+// recordMissing counts one more consecutive check a SKU was missing from the API response.
+// It reports true only on the check that reaches the configured threshold, so the alert is sent once.
+func recordMissing(bot *Bot, sku string) bool {
+	bot.missingChecks[sku]++
+	threshold := bot.appConfig.DiscontinuedAfter
+	return threshold > 0 && bot.missingChecks[sku] == threshold
+}
+
+// recordListed resets the missing count of a SKU seen in the API response
+func recordListed(bot *Bot, sku string) {
+	if isPossiblyDiscontinued(bot, sku) {
+		log.Printf("SKU %s is listed again after %d checks, no longer possibly discontinued", sku, bot.missingChecks[sku])
+	}
+	delete(bot.missingChecks, sku)
+}
+
+// isPossiblyDiscontinued reports whether a SKU has been missing for at least the configured number of checks
+func isPossiblyDiscontinued(bot *Bot, sku string) bool {
+	threshold := bot.appConfig.DiscontinuedAfter
+	return threshold > 0 && bot.missingChecks[sku] >= threshold
+}
+
+func sendDiscontinuedAlert(bot *Bot, sku string) {
+	name := sku
+	if product, exists := bot.productDetails[sku]; exists {
+		name = product.Name
+	}
+	log.Printf("SKU %s was missing from the API response for %d checks, possibly discontinued", sku, bot.missingChecks[sku])
+	message := fmt.Sprintf("⚠️ <b>Possibly Discontinued</b>\n\nProduct: <b>%s</b>\nSKU: %s\nNot listed by Amul for the last %d checks. "+
+		"No further alerts are sent for it until it is listed again.", productLabel(bot.appConfig, name, sku), sku, bot.missingChecks[sku])
+	sendNotificationWithRetry(bot, message, sku, "possibly-discontinued")
+}
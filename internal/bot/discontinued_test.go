@@ -0,0 +1,33 @@
+package bot
+
+import (
+	"amul-notifier/internal/config"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscontinuedDetection(t *testing.T) {
+	t.Run("Report once after the threshold and reset when listed", func(t *testing.T) {
+		bot := &Bot{missingChecks: map[string]int{}, appConfig: &config.AppConfig{DiscontinuedAfter: 3}}
+
+		reports := []bool{}
+		for range 5 {
+			reports = append(reports, recordMissing(bot, "LASCP40_30"))
+		}
+		assert.Equal(t, []bool{false, false, true, false, false}, reports)
+		assert.True(t, isPossiblyDiscontinued(bot, "LASCP40_30"))
+
+		recordListed(bot, "LASCP40_30")
+		assert.False(t, isPossiblyDiscontinued(bot, "LASCP40_30"))
+		assert.False(t, recordMissing(bot, "LASCP40_30"))
+	})
+
+	t.Run("Disabled without a threshold", func(t *testing.T) {
+		bot := &Bot{missingChecks: map[string]int{}, appConfig: &config.AppConfig{}}
+		for range 10 {
+			assert.False(t, recordMissing(bot, "LASCP40_30"))
+		}
+		assert.False(t, isPossiblyDiscontinued(bot, "LASCP40_30"))
+	})
+}
@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"amul-notifier/pkg/amulclient"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+)
+
+// StockEvent is published whenever the stock status of a monitored SKU changes, and when a SKU is first seen
+type StockEvent struct {
+	SKU      string    `json:"sku"`
+	Name     string    `json:"name"`
+	InStock  bool      `json:"in_stock"`
+	Quantity int       `json:"quantity"`
+	Price    int       `json:"price"`
+	Store    string    `json:"store"`
+	At       time.Time `json:"timestamp"`
+}
+
+// publishStockChange sends a stock event to the configured event channels
+func publishStockChange(bot *Bot, product amulclient.Product, inStock bool, at time.Time) {
+	name := product.Name
+	if name == "" {
+		name = product.SKU
+	}
+	event := StockEvent{SKU: product.SKU, Name: name, InStock: inStock, Price: product.Price, Store: bot.store, At: at}
+	if inStock {
+		event.Quantity = product.InventoryQuantity
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error encoding stock event for %s: %v", product.SKU, err)
+		return
+	}
+	topic := strings.ReplaceAll(bot.appConfig.MQTTTopic, "{sku}", product.SKU)
+	if err := bot.mqtt.Publish(topic, payload); err != nil {
+		log.Printf("Error publishing stock event for %s to MQTT topic %s: %v", product.SKU, topic, err)
+	}
+	// Webhook retries can take seconds, they run in the background instead of delaying the check
+	bot.webhooks.Queue(payload, func(err error) {
+		log.Printf("Error sending stock event for %s to webhooks: %v", product.SKU, err)
+	})
+}
@@ -0,0 +1,169 @@
+package bot
+
+import (
+	"amul-notifier/internal/config"
+	"amul-notifier/internal/features"
+	"amul-notifier/pkg/amulclient"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// formatINR renders a rupee amount using the Indian digit grouping (e.g. ₹1,23,456)
+func formatINR(amount int) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := strconv.Itoa(amount)
+	if len(digits) <= 3 {
+		return sign + "₹" + digits
+	}
+
+	// Last three digits form the first group, every group after that has two digits
+	grouped := digits[len(digits)-3:]
+	rest := digits[:len(digits)-3]
+	for len(rest) > 2 {
+		grouped = rest[len(rest)-2:] + "," + grouped
+		rest = rest[:len(rest)-2]
+	}
+	grouped = rest + "," + grouped
+
+	return sign + "₹" + grouped
+}
+
+// discountPercent returns the rounded discount of price against the MRP, 0 if there is none
+func discountPercent(price, mrp int) int {
+	if mrp <= 0 || price >= mrp {
+		return 0
+	}
+	return int(math.Round(float64(mrp-price) * 100 / float64(mrp)))
+}
+
+// formatPriceDetails builds the price line shown in alerts, including MRP and discount when discounted
+func formatPriceDetails(product amulclient.Product) string {
+	if product.Price <= 0 {
+		return "Price: N/A"
+	}
+
+	if product.ComparePrice > product.Price {
+		return fmt.Sprintf("Price: <b>%s</b> (MRP <s>%s</s>, %d%% off)",
+			formatINR(product.Price), formatINR(product.ComparePrice), discountPercent(product.Price, product.ComparePrice))
+	}
+	return fmt.Sprintf("Price: <b>%s</b>", formatINR(product.Price))
+}
+
+// skuAlias returns the configured alias for a SKU or its variant group, preferring the alphabetically first one
+func skuAlias(appConfig *config.AppConfig, sku string) string {
+	variantWildcard := variantGroupKey(sku) + "_*"
+	matchedAlias := ""
+	for alias, target := range appConfig.SKUAliases {
+		if (target == sku || target == variantWildcard) && (matchedAlias == "" || alias < matchedAlias) {
+			matchedAlias = alias
+		}
+	}
+	return matchedAlias
+}
+
+// productLabel returns the escaped product name followed by its alias, if one is configured
+func productLabel(appConfig *config.AppConfig, name, sku string) string {
+	if alias := skuAlias(appConfig, sku); alias != "" {
+		return escapeHTML(fmt.Sprintf("%s (%s)", name, alias))
+	}
+	return escapeHTML(name)
+}
+
+// formatSKUNote returns the note line for a SKU (or its variant group), empty if no note is configured
+func formatSKUNote(appConfig *config.AppConfig, sku string) string {
+	note, exists := appConfig.SKUNotes[sku]
+	if !exists {
+		note, exists = appConfig.SKUNotes[variantGroupKey(sku)+"_*"]
+	}
+	if !exists {
+		return ""
+	}
+	return fmt.Sprintf("\n📝 Note: <i>%s</i>", escapeHTML(note))
+}
+
+// formatUrgencyNote builds the banner placed above alerts for critical SKUs
+func formatUrgencyNote(appConfig *config.AppConfig, product amulclient.Product) string {
+	checkedAt := time.Now()
+	if appConfig.Timezone != nil {
+		checkedAt = checkedAt.In(appConfig.Timezone)
+	}
+	nextCheck := checkedAt.Add(appConfig.CheckInterval)
+	return fmt.Sprintf("🚨 <b>PRIORITY RESTOCK</b> 🚨\n⏳ Only %d left as of %s. Rare items often sell out before the next check at %s, order now!\n\n",
+		product.InventoryQuantity, checkedAt.Format("15:04"), nextCheck.Format("15:04"))
+}
+
+// formatSellThrough describes how fast the last in-stock run sold out, empty without history
+func formatSellThrough(bot *Bot, sku string, soldOutAt time.Time) string {
+	if !bot.features.Enabled(features.RestockInsights, true) {
+		return ""
+	}
+	run, found := bot.history.LastInStockRun(sku, soldOutAt)
+	if !found || run.PeakQuantity <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n📉 Sold through: %d → 0 units in %s", run.PeakQuantity, formatDuration(soldOutAt.Sub(run.Start)))
+}
+
+// Completed out-of-stock spells needed before a restock estimate is shown
+const minRestockGapsForETA = 3
+
+// formatRestockETA estimates when a SKU that just sold out is back, from the median of its past restock gaps
+func formatRestockETA(bot *Bot, sku string) string {
+	if !bot.features.Enabled(features.RestockInsights, true) {
+		return ""
+	}
+	gaps := bot.history.RestockGaps(sku, bot.store)
+	if len(gaps) < minRestockGapsForETA {
+		return ""
+	}
+	slices.Sort(gaps)
+	median := gaps[len(gaps)/2]
+	if len(gaps)%2 == 0 {
+		median = (gaps[len(gaps)/2-1] + gaps[len(gaps)/2]) / 2
+	}
+	return fmt.Sprintf("\n🔮 Usually back within %s (based on %d past restocks)", formatRoundedUpDuration(median), len(gaps))
+}
+
+// formatRoundedUpDuration rounds a duration up to whole days, or whole hours below a day
+func formatRoundedUpDuration(duration time.Duration) string {
+	unit := time.Hour
+	if duration > 24*time.Hour {
+		unit = 24 * time.Hour
+	}
+	return formatDuration(((duration + unit - 1) / unit) * unit)
+}
+
+// formatDuration renders a duration in words at minute precision, e.g. "2 hours 5 minutes"
+func formatDuration(duration time.Duration) string {
+	totalMinutes := int(duration.Round(time.Minute).Minutes())
+	if totalMinutes < 1 {
+		return "less than a minute"
+	}
+
+	days, hours, minutes := totalMinutes/(24*60), (totalMinutes/60)%24, totalMinutes%60
+	parts := []string{}
+	for _, part := range []struct {
+		value int
+		unit  string
+	}{{days, "day"}, {hours, "hour"}, {minutes, "minute"}} {
+		if part.value == 1 {
+			parts = append(parts, "1 "+part.unit)
+		} else if part.value > 1 {
+			parts = append(parts, fmt.Sprintf("%d %ss", part.value, part.unit))
+		}
+	}
+	// Days are precise enough for long durations
+	if days > 0 && len(parts) > 2 {
+		parts = parts[:2]
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"amul-notifier/internal/config"
+	"amul-notifier/pkg/amulclient"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriceFormatting(t *testing.T) {
+	t.Run("Format INR with Indian grouping", func(t *testing.T) {
+		assert.Equal(t, "₹0", formatINR(0))
+		assert.Equal(t, "₹999", formatINR(999))
+		assert.Equal(t, "₹1,000", formatINR(1000))
+		assert.Equal(t, "₹12,345", formatINR(12345))
+		assert.Equal(t, "₹1,23,456", formatINR(123456))
+		assert.Equal(t, "₹12,34,56,789", formatINR(123456789))
+		assert.Equal(t, "-₹1,500", formatINR(-1500))
+	})
+
+	t.Run("Compute discount percentage", func(t *testing.T) {
+		assert.Equal(t, 10, discountPercent(450, 500))
+		assert.Equal(t, 0, discountPercent(500, 500))
+		assert.Equal(t, 0, discountPercent(600, 500))
+		assert.Equal(t, 0, discountPercent(100, 0))
+	})
+
+	t.Run("Price details with and without MRP", func(t *testing.T) {
+		assert.Equal(t, "Price: <b>₹1,200</b>", formatPriceDetails(amulclient.Product{Price: 1200}))
+		assert.Equal(t, "Price: <b>₹1,080</b> (MRP <s>₹1,200</s>, 10% off)", formatPriceDetails(amulclient.Product{Price: 1080, ComparePrice: 1200}))
+		assert.Equal(t, "Price: N/A", formatPriceDetails(amulclient.Product{}))
+	})
+
+	t.Run("Product label with alias", func(t *testing.T) {
+		appConfig := &config.AppConfig{SKUAliases: map[string]string{"rose-lassi": "LASCP40_30", "paneer": "HPPCP01_*"}}
+		assert.Equal(t, "Rose Lassi (rose-lassi)", productLabel(appConfig, "Rose Lassi", "LASCP40_30"))
+		assert.Equal(t, "Paneer (paneer)", productLabel(appConfig, "Paneer", "HPPCP01_24"))
+		assert.Equal(t, "Milk", productLabel(appConfig, "Milk", "HPMCP01_08"))
+	})
+
+	t.Run("Format durations in words", func(t *testing.T) {
+		assert.Equal(t, "less than a minute", formatDuration(20*time.Second))
+		assert.Equal(t, "40 minutes", formatDuration(40*time.Minute))
+		assert.Equal(t, "1 hour 5 minutes", formatDuration(65*time.Minute))
+		assert.Equal(t, "2 days 3 hours", formatDuration(51*time.Hour+10*time.Minute))
+		assert.Equal(t, "5 hours", formatRoundedUpDuration(4*time.Hour+10*time.Minute))
+		assert.Equal(t, "3 days", formatRoundedUpDuration(51*time.Hour))
+	})
+}
@@ -0,0 +1,140 @@
+package bot
+
+import (
+	"amul-notifier/internal/history"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Series exposed per SKU to the Grafana JSON datasource, as "<SKU>:<series>"
+var grafanaSeries = []string{"available", "quantity", "price", "mrp"}
+
+type grafanaQueryRequest struct {
+	Range struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	} `json:"range"`
+	Targets []struct {
+		Target string `json:"target"`
+	} `json:"targets"`
+	MaxDataPoints int `json:"maxDataPoints"`
+}
+
+type grafanaTimeSeries struct {
+	Target string `json:"target"`
+	// [value, unix milliseconds] pairs
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+// registerGrafanaRoutes adds the endpoints of the Grafana JSON datasource plugin under /grafana
+func registerGrafanaRoutes(mux *http.ServeMux, bot *Bot) {
+	mux.HandleFunc("GET /grafana/{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mux.HandleFunc("POST /grafana/search", func(w http.ResponseWriter, r *http.Request) {
+		targets := []string{}
+		for _, sku := range bot.history.SKUs() {
+			for _, series := range grafanaSeries {
+				targets = append(targets, sku+":"+series)
+			}
+		}
+		writeJSON(w, targets)
+	})
+
+	mux.HandleFunc("POST /grafana/query", func(w http.ResponseWriter, r *http.Request) {
+		var query grafanaQueryRequest
+		if err := json.NewDecoder(r.Body).Decode(&query); err != nil {
+			http.Error(w, "invalid query: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		response := []grafanaTimeSeries{}
+		for _, target := range query.Targets {
+			sku, series, found := strings.Cut(target.Target, ":")
+			if !found {
+				continue
+			}
+			response = append(response, grafanaTimeSeries{
+				Target:     target.Target,
+				Datapoints: historyDatapoints(bot, sku, series, query.Range.From, query.Range.To, query.MaxDataPoints),
+			})
+		}
+		writeJSON(w, response)
+	})
+}
+
+// historyDatapoints converts stored records into [value, unix ms] pairs, thinned out to at most maxDataPoints
+func historyDatapoints(bot *Bot, sku, series string, from, to time.Time, maxDataPoints int) [][2]float64 {
+	records := bot.history.Query(sku, from, to)
+
+	stride := 1
+	if maxDataPoints > 0 && len(records) > maxDataPoints {
+		stride = (len(records) + maxDataPoints - 1) / maxDataPoints
+	}
+
+	datapoints := [][2]float64{}
+	for i := 0; i < len(records); i += stride {
+		record := records[i]
+		var value float64
+		switch series {
+		case "available":
+			if record.Available {
+				value = 1
+			}
+		case "quantity":
+			value = float64(record.Quantity)
+		case "price":
+			value = float64(record.Price)
+		case "mrp":
+			value = float64(max(record.ComparePrice, record.Price))
+		default:
+			return datapoints
+		}
+		datapoints = append(datapoints, [2]float64{value, float64(record.At.UnixMilli())})
+	}
+	return datapoints
+}
+
+// registerHistoryExportRoute serves history as CSV, filtered with sku (repeatable), from and to query parameters
+func registerHistoryExportRoute(mux *http.ServeMux, bot *Bot) {
+	mux.HandleFunc("GET /history.csv", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		to := time.Now()
+		from := to.AddDate(0, 0, -30)
+		var err error
+		if value := query.Get("to"); value != "" {
+			if to, err = history.ParseTimeBound(value, true, bot.appConfig.Timezone); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if value := query.Get("from"); value != "" {
+			if from, err = history.ParseTimeBound(value, false, bot.appConfig.Timezone); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		skus := make(map[string]bool)
+		for _, sku := range query["sku"] {
+			skus[sku] = true
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="history.csv"`)
+		if err := history.WriteCSV(w, bot.history.Export(skus, from, to)); err != nil {
+			log.Printf("Error writing history CSV: %v", err)
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("Error writing JSON response: %v", err)
+	}
+}
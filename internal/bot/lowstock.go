@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"amul-notifier/internal/config"
+	"amul-notifier/pkg/amulclient"
+	"fmt"
+	"log"
+)
+
+// lowStockThreshold returns the threshold of a SKU, falling back to its variant group, 0 when none is set
+func lowStockThreshold(appConfig *config.AppConfig, sku string) int {
+	if threshold, exists := appConfig.LowStockThresholds[sku]; exists {
+		return threshold
+	}
+	return appConfig.LowStockThresholds[variantGroupKey(sku)+"_*"]
+}
+
+// droppedBelowThreshold tracks whether an in-stock product is below its threshold and reports true only
+// on the check it drops below, so the alert is sent once until the product is topped up or restocked
+func droppedBelowThreshold(bot *Bot, product amulclient.Product) bool {
+	threshold := lowStockThreshold(bot.appConfig, product.SKU)
+	if threshold == 0 {
+		return false
+	}
+
+	isLow := product.Available == 1 && product.InventoryQuantity < threshold
+	wasLow := bot.lowStockState[product.SKU]
+	bot.lowStockState[product.SKU] = isLow
+	return isLow && !wasLow
+}
+
+// checkLowStock alerts when an in-stock product drops below its low-stock threshold
+func checkLowStock(bot *Bot, product amulclient.Product) {
+	if !droppedBelowThreshold(bot, product) {
+		return
+	}
+
+	threshold := lowStockThreshold(bot.appConfig, product.SKU)
+	log.Printf("Found LOW STOCK: %s (SKU: %s) has %d left, below %d", product.Name, product.SKU, product.InventoryQuantity, threshold)
+	link := ""
+	if product.Alias != "" {
+		link = fmt.Sprintf("\n\n🔗 <a href=\"%s%s\">View on Amul Shop</a>", productBaseURL, product.Alias)
+	}
+
+	message := fmt.Sprintf("📦 <b>Low Stock</b>\n\nProduct: <b>%s</b>\nOnly <b>%d</b> left (alert below %d)\n%s\nSKU: %s%s%s",
+		productLabel(bot.appConfig, product.Name, product.SKU), product.InventoryQuantity, threshold, formatPriceDetails(product),
+		product.SKU, formatSKUNote(bot.appConfig, product.SKU), link)
+	sendNotificationWithRetry(bot, message, product.SKU, "low-stock")
+}
@@ -0,0 +1,34 @@
+package bot
+
+import (
+	"amul-notifier/internal/config"
+	"amul-notifier/pkg/amulclient"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLowStock(t *testing.T) {
+	t.Run("Pick the SKU threshold before the variant group", func(t *testing.T) {
+		appConfig := &config.AppConfig{LowStockThresholds: map[string]int{"HPPCP01_24": 10, "HPPCP01_*": 5}}
+		assert.Equal(t, 10, lowStockThreshold(appConfig, "HPPCP01_24"))
+		assert.Equal(t, 5, lowStockThreshold(appConfig, "HPPCP01_30"))
+		assert.Equal(t, 0, lowStockThreshold(appConfig, "LASCP40_30"))
+	})
+
+	t.Run("Alert once per drop below the threshold", func(t *testing.T) {
+		bot := &Bot{
+			lowStockState: map[string]bool{},
+			appConfig:     &config.AppConfig{LowStockThresholds: map[string]int{"LASCP40_30": 20}},
+		}
+
+		drops := []bool{}
+		for _, quantity := range []int{50, 15, 8, 500, 12} {
+			drops = append(drops, droppedBelowThreshold(bot, amulclient.Product{SKU: "LASCP40_30", Available: 1, InventoryQuantity: quantity}))
+		}
+		assert.Equal(t, []bool{false, true, false, false, true}, drops)
+
+		assert.False(t, droppedBelowThreshold(bot, amulclient.Product{SKU: "LASCP40_30", Available: 0}))
+		assert.True(t, droppedBelowThreshold(bot, amulclient.Product{SKU: "LASCP40_30", Available: 1, InventoryQuantity: 3}))
+	})
+}
@@ -0,0 +1,177 @@
+package bot
+
+import (
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	parseModeHTML       = "HTML"
+	parseModeMarkdownV2 = "MarkdownV2"
+)
+
+// Longest message text Telegram accepts, in UTF-16 code units
+const telegramMessageLimit = 4096
+
+// Longest photo caption Telegram accepts, in UTF-16 code units
+const telegramCaptionLimit = 1024
+
+// Tags used when building messages: <b>, <i>, <s> and <a href="...">
+var messageTagPattern = regexp.MustCompile(`<(/?)(b|i|s|a)(?:\s+href="([^"]*)")?>`)
+
+var markdownV2Escaper = strings.NewReplacer(
+	`\`, `\\`, "_", `\_`, "*", `\*`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`, "~", `\~`, "`", "\\`",
+	">", `\>`, "#", `\#`, "+", `\+`, "-", `\-`, "=", `\=`, "|", `\|`, "{", `\{`, "}", `\}`, ".", `\.`, "!", `\!`,
+)
+
+var markdownV2URLEscaper = strings.NewReplacer(`\`, `\\`, ")", `\)`)
+
+// escapeHTML escapes dynamic text (product names, notes) before it is placed into a message
+func escapeHTML(text string) string {
+	return html.EscapeString(text)
+}
+
+// escapeMarkdownV2 escapes every character MarkdownV2 treats as markup
+func escapeMarkdownV2(text string) string {
+	return markdownV2Escaper.Replace(text)
+}
+
+var markdownV2Markers = map[string]string{"b": "*", "i": "_", "s": "~"}
+
+// Markers of standard Markdown (CommonMark with strikethrough), which accepts the same backslash escapes as MarkdownV2
+var markdownMarkers = map[string]string{"b": "**", "i": "_", "s": "~~"}
+
+// htmlToMarkdownV2 converts a message built with the supported HTML tags into MarkdownV2
+func htmlToMarkdownV2(message string) string {
+	return htmlToMarkdownWith(message, markdownV2Markers)
+}
+
+// htmlToMarkdown converts a message built with the supported HTML tags into standard Markdown, for channels other than Telegram
+func htmlToMarkdown(message string) string {
+	return htmlToMarkdownWith(message, markdownMarkers)
+}
+
+func htmlToMarkdownWith(message string, markers map[string]string) string {
+	var converted strings.Builder
+	linkURLs := []string{}
+	lastEnd := 0
+
+	for _, match := range messageTagPattern.FindAllStringSubmatchIndex(message, -1) {
+		converted.WriteString(escapeMarkdownV2(html.UnescapeString(message[lastEnd:match[0]])))
+		lastEnd = match[1]
+
+		isClosing := message[match[2]:match[3]] == "/"
+		tag := message[match[4]:match[5]]
+		if tag != "a" {
+			converted.WriteString(markers[tag])
+			continue
+		}
+
+		if !isClosing {
+			linkURL := ""
+			if match[6] >= 0 {
+				linkURL = html.UnescapeString(message[match[6]:match[7]])
+			}
+			linkURLs = append(linkURLs, linkURL)
+			converted.WriteString("[")
+		} else if len(linkURLs) > 0 {
+			linkURL := linkURLs[len(linkURLs)-1]
+			linkURLs = linkURLs[:len(linkURLs)-1]
+			converted.WriteString("](" + markdownV2URLEscaper.Replace(linkURL) + ")")
+		}
+	}
+	converted.WriteString(escapeMarkdownV2(html.UnescapeString(message[lastEnd:])))
+
+	return converted.String()
+}
+
+// htmlToWhatsApp converts a message built with the supported HTML tags into WhatsApp formatting, which has no
+// escaping and no link markup, so links are written out after their text
+func htmlToWhatsApp(message string) string {
+	var converted strings.Builder
+	linkURLs := []string{}
+	lastEnd := 0
+
+	for _, match := range messageTagPattern.FindAllStringSubmatchIndex(message, -1) {
+		converted.WriteString(html.UnescapeString(message[lastEnd:match[0]]))
+		lastEnd = match[1]
+
+		isClosing := message[match[2]:match[3]] == "/"
+		tag := message[match[4]:match[5]]
+		if tag != "a" {
+			converted.WriteString(markdownV2Markers[tag])
+			continue
+		}
+
+		if !isClosing {
+			linkURL := ""
+			if match[6] >= 0 {
+				linkURL = html.UnescapeString(message[match[6]:match[7]])
+			}
+			linkURLs = append(linkURLs, linkURL)
+		} else if len(linkURLs) > 0 {
+			linkURL := linkURLs[len(linkURLs)-1]
+			linkURLs = linkURLs[:len(linkURLs)-1]
+			converted.WriteString(": " + linkURL)
+		}
+	}
+	converted.WriteString(html.UnescapeString(message[lastEnd:]))
+
+	return converted.String()
+}
+
+// renderMessage converts a message built with the supported HTML tags into the text sent in the given parse mode
+func renderMessage(message, parseMode string) string {
+	if parseMode == parseModeMarkdownV2 {
+		return htmlToMarkdownV2(message)
+	}
+	return message
+}
+
+// messageLength counts UTF-16 code units, the unit Telegram measures message length in
+func messageLength(text string) int {
+	return len(utf16.Encode([]rune(text)))
+}
+
+// splitMessage splits a message at line breaks into parts that each fit within the limit once rendered.
+// Lines too long to fit on their own are cut, which may break formatting on that line only.
+func splitMessage(message, parseMode string, limit int) []string {
+	fits := func(part string) bool {
+		return messageLength(renderMessage(part, parseMode)) <= limit
+	}
+	if fits(message) {
+		return []string{message}
+	}
+
+	parts := []string{}
+	current := ""
+	for line := range strings.SplitSeq(message, "\n") {
+		candidate := line
+		if current != "" {
+			candidate = current + "\n" + line
+		}
+		if fits(candidate) {
+			current = candidate
+			continue
+		}
+
+		if current != "" {
+			parts = append(parts, current)
+		}
+		current = line
+		for !fits(current) {
+			runes := []rune(current)
+			// At least one rune per part so an impossible limit can't loop forever
+			cut := max(sort.Search(len(runes), func(i int) bool { return !fits(string(runes[:i+1])) }), 1)
+			parts = append(parts, string(runes[:cut]))
+			current = string(runes[cut:])
+		}
+	}
+	if strings.TrimSpace(current) != "" {
+		parts = append(parts, current)
+	}
+	return parts
+}
@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdownV2Conversion(t *testing.T) {
+	t.Run("Escape special characters", func(t *testing.T) {
+		assert.Equal(t, `Stock Available\! \(1\.5 kg\) a\_b`, escapeMarkdownV2("Stock Available! (1.5 kg) a_b"))
+	})
+
+	t.Run("Convert supported tags", func(t *testing.T) {
+		message := "✅ <b>Stock Available!</b>\nProduct: <b>Milk &amp; More</b> <i>note</i> <s>₹500</s>\n🔗 <a href=\"https://shop.amul.com/en/product/a-b\">View on Amul Shop</a>"
+		expected := "✅ *Stock Available\\!*\nProduct: *Milk & More* _note_ ~₹500~\n🔗 [View on Amul Shop](https://shop.amul.com/en/product/a-b)"
+		assert.Equal(t, expected, htmlToMarkdownV2(message))
+	})
+
+	t.Run("Convert supported tags to standard Markdown", func(t *testing.T) {
+		message := "<b>Stock Available!</b> <i>note</i> <s>₹500</s> <a href=\"https://shop.amul.com/en/product/a-b\">View</a>"
+		expected := "**Stock Available\\!** _note_ ~~₹500~~ [View](https://shop.amul.com/en/product/a-b)"
+		assert.Equal(t, expected, htmlToMarkdown(message))
+	})
+
+	t.Run("Convert supported tags to WhatsApp formatting", func(t *testing.T) {
+		message := "<b>Stock Available!</b>\nProduct: <b>Milk &amp; More</b> <s>₹500</s>\n🔗 <a href=\"https://shop.amul.com/en/product/a-b\">View on Amul Shop</a>"
+		expected := "*Stock Available!*\nProduct: *Milk & More* ~₹500~\n🔗 View on Amul Shop: https://shop.amul.com/en/product/a-b"
+		assert.Equal(t, expected, htmlToWhatsApp(message))
+	})
+
+	t.Run("Split long messages at line breaks", func(t *testing.T) {
+		message := "<b>Header</b>\nfirst line\nsecond line"
+		assert.Equal(t, []string{message}, splitMessage(message, parseModeHTML, 100))
+		assert.Equal(t, []string{"<b>Header</b>\nfirst line", "second line"}, splitMessage(message, parseModeHTML, 24))
+
+		// Escaping makes the MarkdownV2 text longer than the HTML it is built from
+		assert.Equal(t, []string{"a.b.c", "d.e.f"}, splitMessage("a.b.c\nd.e.f", parseModeMarkdownV2, 8))
+	})
+
+	t.Run("Cut lines longer than the limit", func(t *testing.T) {
+		assert.Equal(t, []string{"abcd", "efgh", "ij"}, splitMessage("abcdefghij", parseModeHTML, 4))
+		assert.Equal(t, []string{"🥛🥛", "🥛"}, splitMessage("🥛🥛🥛", parseModeHTML, 4))
+	})
+}
@@ -0,0 +1,153 @@
+package bot
+
+import (
+	"amul-notifier/pkg/amulclient"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Window used for the per-SKU availability ratio
+const availabilityWindow = 24 * time.Hour
+
+type stockObservation struct {
+	at      time.Time
+	inStock bool
+}
+
+// Latest state and recent history of one monitored SKU
+type skuMetrics struct {
+	name          string
+	inStock       bool
+	quantity      int
+	price         int
+	lastRestockAt time.Time
+	observations  []stockObservation
+}
+
+// stockMetrics is written by the check loop and read by the HTTP server, so it has its own lock
+type stockMetrics struct {
+	mu          sync.Mutex
+	skus        map[string]*skuMetrics
+	checksTotal int
+	lastCheckAt time.Time
+}
+
+func newStockMetrics() *stockMetrics {
+	return &stockMetrics{skus: make(map[string]*skuMetrics)}
+}
+
+// recordObservation stores the result of one check for a SKU. A change from out of stock to in stock counts as a restock.
+func (m *stockMetrics) recordObservation(product amulclient.Product, inStock bool, observedAt time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sku, exists := m.skus[product.SKU]
+	if !exists {
+		sku = &skuMetrics{}
+		m.skus[product.SKU] = sku
+	}
+
+	if exists && inStock && !sku.inStock {
+		sku.lastRestockAt = observedAt
+	}
+	if product.Name != "" {
+		sku.name = product.Name
+	}
+	sku.inStock = inStock
+	sku.quantity = product.InventoryQuantity
+	if product.Price > 0 {
+		sku.price = product.Price
+	}
+
+	cutoff := observedAt.Add(-availabilityWindow)
+	sku.observations = slices.DeleteFunc(sku.observations, func(o stockObservation) bool { return o.at.Before(cutoff) })
+	sku.observations = append(sku.observations, stockObservation{at: observedAt, inStock: inStock})
+}
+
+func (m *stockMetrics) recordCheck(checkedAt time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.checksTotal++
+	m.lastCheckAt = checkedAt
+}
+
+// availabilityRatio returns the share of observations within the window that were in stock
+func (s *skuMetrics) availabilityRatio() float64 {
+	if len(s.observations) == 0 {
+		return 0
+	}
+	inStockCount := 0
+	for _, observation := range s.observations {
+		if observation.inStock {
+			inStockCount++
+		}
+	}
+	return float64(inStockCount) / float64(len(s.observations))
+}
+
+// writePrometheus renders the metrics in the Prometheus text exposition format
+func (m *stockMetrics) writePrometheus(w io.Writer, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	skuNames := make([]string, 0, len(m.skus))
+	for sku := range m.skus {
+		skuNames = append(skuNames, sku)
+	}
+	slices.Sort(skuNames)
+
+	writeMetricHeader(w, "amul_checks_total", "counter", "Number of completed stock checks.")
+	fmt.Fprintf(w, "amul_checks_total %d\n", m.checksTotal)
+	if !m.lastCheckAt.IsZero() {
+		writeMetricHeader(w, "amul_last_check_timestamp_seconds", "gauge", "Unix time of the last completed stock check.")
+		fmt.Fprintf(w, "amul_last_check_timestamp_seconds %d\n", m.lastCheckAt.Unix())
+	}
+
+	writeMetricHeader(w, "amul_sku_in_stock", "gauge", "1 if the SKU was in stock at the last check, 0 otherwise.")
+	for _, sku := range skuNames {
+		inStock := 0
+		if m.skus[sku].inStock {
+			inStock = 1
+		}
+		fmt.Fprintf(w, "amul_sku_in_stock{%s} %d\n", skuLabels(sku, m.skus[sku].name), inStock)
+	}
+
+	writeMetricHeader(w, "amul_sku_inventory_quantity", "gauge", "Inventory quantity reported at the last check.")
+	for _, sku := range skuNames {
+		fmt.Fprintf(w, "amul_sku_inventory_quantity{%s} %d\n", skuLabels(sku, m.skus[sku].name), m.skus[sku].quantity)
+	}
+
+	writeMetricHeader(w, "amul_sku_price_rupees", "gauge", "Selling price reported at the last check.")
+	for _, sku := range skuNames {
+		if m.skus[sku].price > 0 {
+			fmt.Fprintf(w, "amul_sku_price_rupees{%s} %d\n", skuLabels(sku, m.skus[sku].name), m.skus[sku].price)
+		}
+	}
+
+	writeMetricHeader(w, "amul_sku_seconds_since_last_restock", "gauge", "Seconds since the SKU last changed from out of stock to in stock, absent until a restock is seen.")
+	for _, sku := range skuNames {
+		if !m.skus[sku].lastRestockAt.IsZero() {
+			fmt.Fprintf(w, "amul_sku_seconds_since_last_restock{%s} %.0f\n", skuLabels(sku, m.skus[sku].name), now.Sub(m.skus[sku].lastRestockAt).Seconds())
+		}
+	}
+
+	writeMetricHeader(w, "amul_sku_availability_ratio_24h", "gauge", "Share of checks in the last 24 hours that found the SKU in stock.")
+	for _, sku := range skuNames {
+		fmt.Fprintf(w, "amul_sku_availability_ratio_24h{%s} %.4f\n", skuLabels(sku, m.skus[sku].name), m.skus[sku].availabilityRatio())
+	}
+}
+
+func writeMetricHeader(w io.Writer, name, metricType, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+var prometheusLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func skuLabels(sku, name string) string {
+	return fmt.Sprintf(`sku="%s",name="%s"`, prometheusLabelEscaper.Replace(sku), prometheusLabelEscaper.Replace(name))
+}
@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"amul-notifier/pkg/amulclient"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStockMetrics(t *testing.T) {
+	t.Run("Track restocks and availability ratio", func(t *testing.T) {
+		metrics := newStockMetrics()
+		start := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+		product := amulclient.Product{SKU: "LASCP40_30", Name: "Rose \"Lassi\"", InventoryQuantity: 50, Price: 450}
+
+		metrics.recordObservation(product, false, start.Add(-25*time.Hour))
+		metrics.recordObservation(product, false, start)
+		metrics.recordObservation(product, true, start.Add(time.Hour))
+		metrics.recordObservation(product, true, start.Add(2*time.Hour))
+		metrics.recordObservation(product, false, start.Add(3*time.Hour))
+		metrics.recordCheck(start.Add(3 * time.Hour))
+
+		sku := metrics.skus["LASCP40_30"]
+		assert.Equal(t, start.Add(time.Hour), sku.lastRestockAt)
+		assert.Equal(t, 4, len(sku.observations))
+		assert.Equal(t, 0.5, sku.availabilityRatio())
+
+		var output strings.Builder
+		metrics.writePrometheus(&output, start.Add(4*time.Hour))
+		assert.Contains(t, output.String(), `amul_sku_in_stock{sku="LASCP40_30",name="Rose \"Lassi\""} 0`)
+		assert.Contains(t, output.String(), `amul_sku_seconds_since_last_restock{sku="LASCP40_30",name="Rose \"Lassi\""} 10800`)
+		assert.Contains(t, output.String(), `amul_sku_availability_ratio_24h{sku="LASCP40_30",name="Rose \"Lassi\""} 0.5000`)
+		assert.Contains(t, output.String(), "amul_checks_total 1")
+	})
+}
@@ -0,0 +1,137 @@
+package bot
+
+import (
+	"amul-notifier/internal/outbox"
+	"log"
+	"time"
+)
+
+// Wait between the attempts of one delivery
+var deliveryRetryDelay = 2 * time.Second
+
+// Notification is one message built with the supported HTML tags, along with how it should be delivered
+type Notification struct {
+	Message string
+	SKU     string
+	Type    string
+	options messageOptions
+}
+
+// Notifier delivers notifications to one recipient of a channel. Send makes a single attempt, retries and the
+// outbox are handled by the dispatcher.
+type Notifier interface {
+	// Channel names the channel in the outbox, e.g. telegram or pushover
+	Channel() string
+	// Recipient is the chat, phone number or user on the channel, empty when the channel has a single one
+	Recipient() string
+	Send(notification Notification) error
+}
+
+// notifierLabel identifies a notifier in logs and recent notifications. Telegram chats keep their bare chat ID.
+func notifierLabel(notifier Notifier) string {
+	switch {
+	case notifier.Channel() == "telegram":
+		return notifier.Recipient()
+	case notifier.Recipient() == "":
+		return notifier.Channel()
+	default:
+		return notifier.Channel() + ":" + notifier.Recipient()
+	}
+}
+
+// Notification types reporting the stock status of a product
+var stockChangeTypes = map[string]bool{"in-stock": true, "out-of-stock": true, "assumed-out-of-stock": true}
+
+// activeNotifiers returns a notifier for every active Telegram chat, followed by the other configured channels.
+// Stock changes also go to the broadcast channel, the in-stock alerts repeated while a product stays in stock don't.
+func activeNotifiers(bot *Bot, notification Notification) []Notifier {
+	chatIDs := activeDeliveryChatIDs(bot)
+	if channelID := bot.appConfig.TelegramChannelID; channelID != "" && notification.options.stockChange && isChatActive(bot, channelID) {
+		chatIDs = append(chatIDs, channelID)
+	}
+
+	notifiers := []Notifier{}
+	for _, chatID := range chatIDs {
+		notifiers = append(notifiers, &telegramNotifier{chatID: chatID, appConfig: bot.appConfig})
+	}
+	return append(notifiers, bot.notifiers...)
+}
+
+// findNotifier returns the notifier for an outbox entry, nil when its channel or chat no longer receives notifications
+func findNotifier(bot *Bot, channel, recipient string) Notifier {
+	if channel == "" || channel == "telegram" {
+		if !isChatActive(bot, recipient) {
+			return nil
+		}
+		return &telegramNotifier{chatID: recipient, appConfig: bot.appConfig}
+	}
+	for _, notifier := range bot.notifiers {
+		if notifier.Channel() == channel && notifier.Recipient() == recipient {
+			return notifier
+		}
+	}
+	return nil
+}
+
+// dispatch fans a notification out to every active notifier. Each one is retried on its own so a failing channel
+// doesn't cause duplicates in the others.
+func dispatch(bot *Bot, notification Notification) {
+	notifiers := activeNotifiers(bot, notification)
+	for i, notifier := range notifiers {
+		entryID := bot.outbox.Add(outbox.Entry{
+			Channel:          notifier.Channel(),
+			ChatID:           notifier.Recipient(),
+			SKU:              notification.SKU,
+			NotificationType: notification.Type,
+			Message:          notification.Message,
+			Silent:           notification.options.silent,
+			Pin:              notification.options.pin,
+			ThreadID:         notification.options.threadID,
+			PhotoURL:         notification.options.photoURL,
+		})
+		deliverWithRetry(bot, entryID, notifier, notification)
+		if len(notifiers) > 1 && ((i+1)%broadcastProgressEvery == 0 || i+1 == len(notifiers)) {
+			log.Printf("Notification (%s) for %s: %d/%d recipients processed", notification.Type, notification.SKU, i+1, len(notifiers))
+		}
+	}
+}
+
+// deliverWithRetry sends a notification to one notifier up to 3 times, recording each attempt in the outbox
+func deliverWithRetry(bot *Bot, entryID int64, notifier Notifier, notification Notification) {
+	label := notifierLabel(notifier)
+	var notifErr error
+	for attempts := range 3 {
+		notifErr = notifier.Send(notification)
+		bot.outbox.RecordAttempt(entryID, notifErr)
+		if notifErr == nil {
+			log.Printf("Notification (%s) sent successfully for %s to %s (Attempt %d).", notification.Type, notification.SKU, label, attempts+1)
+			recordNotification(bot, notification.Type, notification.SKU, label, nil)
+			return
+		}
+
+		log.Printf("Attempt %d: Error sending notification (%s) for %s to %s: %v",
+			attempts+1, notification.Type, notification.SKU, label, notifErr)
+
+		// Retrying cannot help when the bot has been blocked or removed from the chat
+		if isChatUnreachable(notifErr) {
+			markChatInactive(bot, notifier.Recipient())
+			break
+		}
+
+		if attempts < 2 {
+			time.Sleep(deliveryRetryDelay)
+		}
+	}
+
+	// Outages outlast a few seconds of retries, the outbox keeps the notification for later checks
+	if !isChatUnreachable(notifErr) {
+		if nextAttemptAt, retry := bot.outbox.RetryLater(entryID); retry {
+			log.Printf("Notification (%s) for %s to %s failed, retrying at %s: %v", notification.Type, notification.SKU, label, nextAttemptAt.Format(time.TimeOnly), notifErr)
+			recordNotification(bot, notification.Type, notification.SKU, label, notifErr)
+			return
+		}
+	}
+	bot.outbox.MarkDeadLetter(entryID)
+	log.Printf("FAILED to send notification (%s) for %s to %s, moved to dead letters: %v", notification.Type, notification.SKU, label, notifErr)
+	recordNotification(bot, notification.Type, notification.SKU, label, notifErr)
+}
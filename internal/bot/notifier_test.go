@@ -0,0 +1,142 @@
+package bot
+
+import (
+	"amul-notifier/internal/config"
+	"amul-notifier/internal/outbox"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeNotifier records what it sends and answers with err
+type fakeNotifier struct {
+	channel   string
+	recipient string
+	err       error
+	sent      []Notification
+}
+
+func (n *fakeNotifier) Channel() string   { return n.channel }
+func (n *fakeNotifier) Recipient() string { return n.recipient }
+
+func (n *fakeNotifier) Send(notification Notification) error {
+	n.sent = append(n.sent, notification)
+	return n.err
+}
+
+func TestNotifiers(t *testing.T) {
+	t.Run("Deliver and record a notification", func(t *testing.T) {
+		bot := &Bot{appConfig: &config.AppConfig{}}
+		notifier := &fakeNotifier{channel: "whatsapp", recipient: "919876543210"}
+		deliverWithRetry(bot, 0, notifier, Notification{Message: "<b>Stock Available!</b>", SKU: "LASCP40_30", Type: "in-stock"})
+
+		assert.Len(t, notifier.sent, 1)
+		assert.Len(t, bot.recentNotifications, 1)
+		assert.Equal(t, "whatsapp:919876543210", bot.recentNotifications[0].ChatID)
+		assert.Empty(t, bot.recentNotifications[0].Error)
+	})
+
+	t.Run("Stop retrying a chat that blocked the bot", func(t *testing.T) {
+		bot := &Bot{inactiveChats: map[string]time.Time{}, appConfig: &config.AppConfig{TelegramChatId: "100"}}
+		notifier := &fakeNotifier{channel: "telegram", recipient: "42", err: &telegramAPIError{StatusCode: http.StatusForbidden}}
+		deliverWithRetry(bot, 0, notifier, Notification{SKU: "LASCP40_30", Type: "in-stock"})
+
+		assert.Len(t, notifier.sent, 1)
+		assert.Contains(t, bot.inactiveChats, "42")
+		assert.NotEmpty(t, bot.recentNotifications[0].Error)
+	})
+
+	t.Run("Dead-letter a notification once its retries are used up", func(t *testing.T) {
+		deliveryRetryDelay = 0
+		defer func() { deliveryRetryDelay = 2 * time.Second }()
+
+		notificationOutbox, err := outbox.Open(filepath.Join(t.TempDir(), "outbox.json"), nil)
+		assert.NoError(t, err)
+		pushover := &fakeNotifier{channel: "pushover", err: errors.New("service unavailable")}
+		bot := &Bot{outbox: notificationOutbox, notifiers: []Notifier{pushover}, appConfig: &config.AppConfig{}}
+
+		notification := Notification{Message: "<b>Stock Available!</b>", SKU: "LASCP40_30", Type: "in-stock"}
+		deliverWithRetry(bot, bot.outbox.Add(outbox.Entry{Channel: "pushover", SKU: "LASCP40_30", NotificationType: "in-stock"}), pushover, notification)
+		for retries := 0; len(bot.outbox.Pending()) == 1; retries++ {
+			if !assert.Less(t, retries, 10, "entry never left the pending queue") {
+				break
+			}
+			retryDueNotifications(bot, bot.outbox.Pending()[0].NextAttemptAt)
+		}
+
+		deadLetters := bot.outbox.DeadLetters()
+		assert.Len(t, deadLetters, 1)
+		assert.Equal(t, 8, deadLetters[0].Retries)
+		assert.Len(t, pushover.sent, 3*9)
+	})
+
+	t.Run("Post only stock changes to the broadcast channel", func(t *testing.T) {
+		bot := &Bot{inactiveChats: map[string]time.Time{}, appConfig: &config.AppConfig{TelegramChatId: "100", TelegramChannelID: "@amul_protein_stock"}}
+
+		recipients := func(notifiers []Notifier) []string {
+			labels := []string{}
+			for _, notifier := range notifiers {
+				labels = append(labels, notifierLabel(notifier))
+			}
+			return labels
+		}
+		restock := Notification{SKU: "LASCP40_30", Type: "in-stock", options: messageOptions{stockChange: true}}
+		assert.Equal(t, []string{"100", "@amul_protein_stock"}, recipients(activeNotifiers(bot, restock)))
+		assert.Equal(t, []string{"100"}, recipients(activeNotifiers(bot, Notification{SKU: "LASCP40_30", Type: "in-stock"})))
+		assert.Equal(t, []string{"100"}, recipients(activeNotifiers(bot, Notification{Type: "briefing"})))
+	})
+
+	t.Run("Post a restock to the broadcast channel once, not with every in-stock check", func(t *testing.T) {
+		// Telegram requests are answered here, everything else goes to the fake shop
+		var posted []string
+		defaultTransport := http.DefaultTransport
+		http.DefaultTransport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Host != "api.telegram.org" {
+				return defaultTransport.RoundTrip(req)
+			}
+			var payload struct {
+				ChatID string `json:"chat_id"`
+			}
+			json.NewDecoder(req.Body).Decode(&payload)
+			posted = append(posted, payload.ChatID)
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"ok":true,"result":{}}`)), Request: req}, nil
+		})
+		defer func() { http.DefaultTransport = defaultTransport }()
+
+		products := `{"sku":"LASCP40_30","name":"Rose Lassi","available":1,"inventory_quantity":12,"price":400}`
+		bot := newTestShopBot(t, &config.AppConfig{
+			MonitoredSKUsMap:  map[string]bool{"LASCP40_30": true},
+			TelegramBotToken:  "token",
+			TelegramChannelID: "@amul_protein_stock",
+		}, &products)
+
+		CheckTargetStock(bot)
+		CheckTargetStock(bot)
+		assert.Equal(t, []string{"@amul_protein_stock"}, posted)
+
+		products = `{"sku":"LASCP40_30","name":"Rose Lassi","available":0}`
+		CheckTargetStock(bot)
+		assert.Equal(t, []string{"@amul_protein_stock", "@amul_protein_stock"}, posted)
+	})
+
+	t.Run("Find the notifier of an outbox entry", func(t *testing.T) {
+		pushover := &fakeNotifier{channel: "pushover"}
+		bot := &Bot{
+			inactiveChats: map[string]time.Time{"42": time.Now()},
+			notifiers:     []Notifier{pushover},
+			appConfig:     &config.AppConfig{TelegramChatId: "100"},
+		}
+
+		assert.Equal(t, pushover, findNotifier(bot, "pushover", ""))
+		assert.Nil(t, findNotifier(bot, "gotify", ""))
+		assert.Nil(t, findNotifier(bot, "telegram", "42"))
+		assert.Equal(t, "100", findNotifier(bot, "", "100").Recipient())
+	})
+}
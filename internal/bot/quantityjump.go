@@ -0,0 +1,37 @@
+package bot
+
+import (
+	"amul-notifier/pkg/amulclient"
+	"fmt"
+	"log"
+)
+
+// quantityJump returns how much the quantity grew between two checks, reporting true when it grew by at least
+// minJump. Amul often tops up stock without toggling availability, so the flag is not looked at.
+func quantityJump(previous, current amulclient.Product, minJump int) (int, bool) {
+	jump := current.InventoryQuantity - previous.InventoryQuantity
+	return jump, minJump > 0 && jump >= minJump
+}
+
+// checkQuantityJump alerts when a product's quantity grew by at least --quantity-jump units since the last check
+func checkQuantityJump(bot *Bot, previous, product amulclient.Product) {
+	jump, jumped := quantityJump(previous, product, bot.appConfig.QuantityJump)
+	if !jumped {
+		return
+	}
+
+	log.Printf("Found QUANTITY JUMP: %s (SKU: %s) went from %d to %d", product.Name, product.SKU, previous.InventoryQuantity, product.InventoryQuantity)
+	stockStatusStr := "OUT OF STOCK"
+	if product.Available == 1 {
+		stockStatusStr = "IN STOCK"
+	}
+	link := ""
+	if product.Alias != "" {
+		link = fmt.Sprintf("\n\n🔗 <a href=\"%s%s\">View on Amul Shop</a>", productBaseURL, product.Alias)
+	}
+
+	message := fmt.Sprintf("📈 <b>Stock Topped Up</b>\n\nProduct: <b>%s</b>\nQuantity: %d → <b>%d</b> (+%d)\nStatus: <b>%s</b>\nSKU: %s%s",
+		productLabel(bot.appConfig, product.Name, product.SKU), previous.InventoryQuantity, product.InventoryQuantity, jump,
+		stockStatusStr, product.SKU, link)
+	sendNotificationWithRetry(bot, message, product.SKU, "quantity-jump")
+}
@@ -0,0 +1,26 @@
+package bot
+
+import (
+	"amul-notifier/pkg/amulclient"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuantityJump(t *testing.T) {
+	t.Run("Report jumps of at least the minimum", func(t *testing.T) {
+		jump, jumped := quantityJump(amulclient.Product{InventoryQuantity: 5}, amulclient.Product{InventoryQuantity: 500}, 100)
+		assert.Equal(t, 495, jump)
+		assert.True(t, jumped)
+
+		_, jumped = quantityJump(amulclient.Product{InventoryQuantity: 5, Available: 1}, amulclient.Product{InventoryQuantity: 104, Available: 1}, 100)
+		assert.False(t, jumped)
+		_, jumped = quantityJump(amulclient.Product{InventoryQuantity: 500}, amulclient.Product{InventoryQuantity: 5}, 100)
+		assert.False(t, jumped)
+	})
+
+	t.Run("Disabled without a minimum", func(t *testing.T) {
+		_, jumped := quantityJump(amulclient.Product{InventoryQuantity: 0}, amulclient.Product{InventoryQuantity: 1000}, 0)
+		assert.False(t, jumped)
+	})
+}
@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"amul-notifier/internal/statestore"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// What each notification type suppressed during quiet hours reads as in the summary, types missing here
+// (briefings, digests, check timings) are sent later anyway or only matter when they happen
+var quietHoursSummaryEvents = map[string]string{
+	"in-stock":              "came back IN STOCK",
+	"out-of-stock":          "went OUT OF STOCK",
+	"assumed-out-of-stock":  "went OUT OF STOCK",
+	"on-offer":              "went on offer",
+	"low-stock":             "was running low",
+	"quantity-jump":         "was restocked",
+	"possibly-discontinued": "may have been discontinued",
+}
+
+// recordSuppressedAlert keeps an alert suppressed during quiet hours for the summary sent when they end.
+// In-stock alerts repeat every check, so an alert repeating the previous one of its SKU is dropped.
+func recordSuppressedAlert(bot *Bot, sku, notificationType string, at time.Time) {
+	if _, summarized := quietHoursSummaryEvents[notificationType]; !summarized {
+		return
+	}
+	for _, alert := range slices.Backward(bot.suppressedAlerts) {
+		if alert.SKU == sku {
+			if alert.Type == notificationType {
+				return
+			}
+			break
+		}
+	}
+	bot.suppressedAlerts = append(bot.suppressedAlerts, statestore.SuppressedAlert{At: at, SKU: sku, Type: notificationType})
+	saveState(bot)
+}
+
+// SendQuietHoursSummary sends the alerts suppressed during quiet hours as one message once they are over.
+// While notifications are paused the alerts are kept, so the summary goes out when the pause ends.
+func SendQuietHoursSummary(bot *Bot) {
+	if len(bot.suppressedAlerts) == 0 || isPaused(bot.appConfig, time.Now()) || isQuietHours(bot.appConfig) {
+		return
+	}
+
+	log.Printf("Sending summary of %d alerts suppressed during quiet hours", len(bot.suppressedAlerts))
+	message := buildQuietHoursSummary(bot)
+	bot.suppressedAlerts = nil
+	saveState(bot)
+	sendNotificationWithRetry(bot, message, "", "quiet-hours-summary")
+}
+
+// buildQuietHoursSummary lists the suppressed alerts in order, followed by the current stock of their products
+func buildQuietHoursSummary(bot *Bot) string {
+	location := bot.appConfig.Timezone
+	if location == nil {
+		location = time.Local
+	}
+
+	label := func(sku string) string {
+		name := sku
+		if product, exists := bot.productDetails[sku]; exists {
+			name = product.Name
+		}
+		return productLabel(bot.appConfig, name, sku)
+	}
+
+	events := []string{}
+	skus := []string{}
+	for _, alert := range bot.suppressedAlerts {
+		events = append(events, fmt.Sprintf("🌙 %s %s %s", alert.At.In(location).Format("15:04"), label(alert.SKU), quietHoursSummaryEvents[alert.Type]))
+		if !slices.Contains(skus, alert.SKU) {
+			skus = append(skus, alert.SKU)
+		}
+	}
+
+	var inStock, outOfStock []string
+	for _, sku := range skus {
+		if bot.productStockState[sku] {
+			inStock = append(inStock, "✅ "+label(sku))
+		} else {
+			outOfStock = append(outOfStock, "❌ "+label(sku))
+		}
+	}
+
+	var summary strings.Builder
+	summary.WriteString("🌅 <b>While you were sleeping</b>\n")
+	writeBriefingSection(&summary, "During quiet hours", events)
+	writeBriefingSection(&summary, "In stock now", inStock)
+	writeBriefingSection(&summary, "Out of stock now", outOfStock)
+	return summary.String()
+}
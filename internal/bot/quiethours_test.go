@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"amul-notifier/internal/config"
+	"amul-notifier/pkg/amulclient"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuietHoursSummary(t *testing.T) {
+	t.Run("Summarize what happened during quiet hours", func(t *testing.T) {
+		bot := &Bot{
+			productStockState: map[string]bool{"LASCP40_30": false, "HPPCP01_24": true},
+			productDetails: map[string]amulclient.Product{
+				"LASCP40_30": {SKU: "LASCP40_30", Name: "Rose Lassi"},
+			},
+			appConfig: &config.AppConfig{Timezone: time.UTC},
+		}
+		night := time.Date(2025, 5, 2, 2, 0, 0, 0, time.UTC)
+
+		recordSuppressedAlert(bot, "LASCP40_30", "in-stock", night)
+		// In-stock alerts repeat with every check while the product stays in stock
+		recordSuppressedAlert(bot, "LASCP40_30", "in-stock", night.Add(30*time.Minute))
+		recordSuppressedAlert(bot, "HPPCP01_24", "in-stock", night.Add(45*time.Minute))
+		recordSuppressedAlert(bot, "LASCP40_30", "out-of-stock", night.Add(time.Hour))
+		recordSuppressedAlert(bot, "LASCP40_30", "slow-check", night.Add(2*time.Hour))
+		assert.Len(t, bot.suppressedAlerts, 3)
+
+		assert.Equal(t, "🌅 <b>While you were sleeping</b>\n"+
+			"\n<b>During quiet hours</b>\n"+
+			"🌙 02:00 Rose Lassi came back IN STOCK\n"+
+			"🌙 02:45 HPPCP01_24 came back IN STOCK\n"+
+			"🌙 03:00 Rose Lassi went OUT OF STOCK\n"+
+			"\n<b>In stock now</b>\n✅ HPPCP01_24\n"+
+			"\n<b>Out of stock now</b>\n❌ Rose Lassi\n", buildQuietHoursSummary(bot))
+	})
+
+	t.Run("Keep the summary while notifications are paused", func(t *testing.T) {
+		bot := &Bot{appConfig: &config.AppConfig{Timezone: time.UTC, PauseUntil: time.Now().Add(time.Hour)}}
+		recordSuppressedAlert(bot, "LASCP40_30", "in-stock", time.Now().Add(-time.Hour))
+
+		SendQuietHoursSummary(bot)
+		assert.Len(t, bot.suppressedAlerts, 1)
+	})
+}
@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"crypto/subtle"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+// StartHTTPServer serves the metrics, stock and history endpoints in the background. Errors are logged since the notifier keeps working without it.
+func StartHTTPServer(bot *Bot) {
+	if bot.appConfig.HTTPAddr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		bot.metrics.writePrometheus(w, time.Now())
+	})
+	registerAPIRoutes(mux, bot)
+	if bot.history != nil {
+		registerGrafanaRoutes(mux, bot)
+		registerHistoryExportRoute(mux, bot)
+	}
+
+	var handler http.Handler = mux
+	if bot.appConfig.HTTPToken != "" {
+		handler = requireBearerToken(bot.appConfig.HTTPToken, mux)
+	} else if !isLoopbackAddr(bot.appConfig.HTTPAddr) {
+		log.Printf("Warning: HTTP server on %s has no token, anyone who can reach it can read the stock history. Set --http-token or bind to 127.0.0.1.", bot.appConfig.HTTPAddr)
+	}
+
+	server := &http.Server{
+		Addr:              bot.appConfig.HTTPAddr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Printf("HTTP server listening on %s", bot.appConfig.HTTPAddr)
+		if err := server.ListenAndServe(); err != nil {
+			log.Printf("Error: HTTP server stopped: %v", err)
+		}
+	}()
+}
+
+// requireBearerToken rejects requests without an "Authorization: Bearer <token>" header
+func requireBearerToken(token string, next http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="amul-notifier"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLoopbackAddr reports whether a listen address only accepts local connections, e.g. 127.0.0.1:9090
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
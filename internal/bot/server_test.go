@@ -0,0 +1,39 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPServer(t *testing.T) {
+	t.Run("Require the bearer token", func(t *testing.T) {
+		handler := requireBearerToken("s3cret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		get := func(authorization string) int {
+			request := httptest.NewRequest(http.MethodGet, "/history.csv", nil)
+			if authorization != "" {
+				request.Header.Set("Authorization", authorization)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+			return recorder.Code
+		}
+
+		assert.Equal(t, http.StatusOK, get("Bearer s3cret"))
+		assert.Equal(t, http.StatusUnauthorized, get(""))
+		assert.Equal(t, http.StatusUnauthorized, get("Bearer wrong"))
+		assert.Equal(t, http.StatusUnauthorized, get("s3cret"))
+	})
+
+	t.Run("Detect loopback addresses", func(t *testing.T) {
+		assert.True(t, isLoopbackAddr("127.0.0.1:9090"))
+		assert.True(t, isLoopbackAddr("localhost:9090"))
+		assert.True(t, isLoopbackAddr("[::1]:9090"))
+		assert.False(t, isLoopbackAddr(":9090"))
+		assert.False(t, isLoopbackAddr("0.0.0.0:9090"))
+	})
+}
@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Number of recent notifications kept for status views
+const recentNotificationsLimit = 20
+
+// Status is a point-in-time view of the monitored products and recent notifications, for dashboards
+type Status struct {
+	Store         string
+	Products      []ProductStatus
+	Notifications []NotificationRecord
+}
+
+type ProductStatus struct {
+	SKU      string `json:"sku"`
+	Name     string `json:"name"`
+	InStock  bool   `json:"in_stock"`
+	Quantity int    `json:"quantity"`
+	Price    int    `json:"price"`
+	// Missing from the API response for at least --discontinued-after checks
+	Discontinued bool `json:"possibly_discontinued"`
+}
+
+// Outcome of one notification delivery to one chat
+type NotificationRecord struct {
+	At               time.Time
+	NotificationType string
+	SKU              string
+	ChatID           string
+	Error            string
+}
+
+// CurrentStatus snapshots the bot state. It must be called from the goroutine running the checks.
+func CurrentStatus(bot *Bot) Status {
+	products := []ProductStatus{}
+	for sku := range trackedSKUs(bot) {
+		product, exists := bot.productDetails[sku]
+		name := sku
+		if exists {
+			name = product.Name
+		}
+		products = append(products, ProductStatus{
+			SKU:          sku,
+			Name:         name,
+			InStock:      bot.productStockState[sku],
+			Quantity:     product.InventoryQuantity,
+			Price:        product.Price,
+			Discontinued: isPossiblyDiscontinued(bot, sku),
+		})
+	}
+	slices.SortFunc(products, func(a, b ProductStatus) int { return strings.Compare(a.SKU, b.SKU) })
+
+	return Status{
+		Store:         bot.store,
+		Products:      products,
+		Notifications: slices.Clone(bot.recentNotifications),
+	}
+}
+
+// recordNotification remembers a delivery outcome, keeping only the most recent ones
+func recordNotification(bot *Bot, notificationType, sku, chatID string, err error) {
+	record := NotificationRecord{At: time.Now(), NotificationType: notificationType, SKU: sku, ChatID: chatID}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	bot.recentNotifications = append(bot.recentNotifications, record)
+	if len(bot.recentNotifications) > recentNotificationsLimit {
+		bot.recentNotifications = bot.recentNotifications[len(bot.recentNotifications)-recentNotificationsLimit:]
+	}
+}
@@ -2,8 +2,11 @@ package bot
 
 import (
 	"amul-notifier/internal/config"
+	"amul-notifier/internal/outbox"
+	"amul-notifier/pkg/amulclient"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -13,10 +16,14 @@ import (
 )
 
 func StartupTestNotification(appConfig *config.AppConfig) error {
-	testMessage := fmt.Sprintf("Amul Stock Notifier started successfully! Monitoring %d SKUs. Quiet hours: %d:00-%d:00 %s.", len(appConfig.MonitoredSKUsMap), quietHourStart, quietHourEnd, appConfig.Timezone.String())
-	err := sendTelegramNotification(testMessage, appConfig)
+	if isPaused(appConfig, time.Now()) {
+		log.Printf("Test notification suppressed, notifications are paused until %s.", appConfig.PauseUntil.Format(time.RFC3339))
+		return nil
+	}
+	testMessage := fmt.Sprintf("Amul Stock Notifier started successfully! Monitoring %d SKUs and %d products in any pack size. Quiet hours: %s.", len(appConfig.MonitoredSKUsMap), len(appConfig.MonitoredVariantsMap), formatQuietHours(appConfig))
+	err := sendTelegramNotification(testMessage, notificationOptions(appConfig, "", "startup", false), appConfig)
 	if err != nil {
-		if !isQuietHours(appConfig.Timezone) {
+		if !isQuietHours(appConfig) {
 			return err
 			// log.Fatalf("Failed to send test notification (outside quiet hours): %v. Check Telegram config.", err)
 		} else {
@@ -28,48 +35,295 @@ func StartupTestNotification(appConfig *config.AppConfig) error {
 	return nil
 }
 
-func isQuietHours(loc *time.Location) bool {
-	if loc == nil {
+func isQuietHours(appConfig *config.AppConfig) bool {
+	if appConfig.Timezone == nil {
 		log.Printf("Warning: Time location is nil, cannot check quiet hours. Assuming it's NOT quiet hours.")
 		return false
 	}
-	currentTime := time.Now().In(loc)
-	currentHour := currentTime.Hour()
-	return currentHour >= quietHourStart && currentHour < quietHourEnd
+	return isQuietTime(appConfig, time.Now().In(appConfig.Timezone))
+}
+
+// isQuietTime reports whether a local time falls within quiet hours, which may wrap past midnight
+func isQuietTime(appConfig *config.AppConfig, at time.Time) bool {
+	start, end := appConfig.QuietHoursStart, appConfig.QuietHoursEnd
+	minute := at.Hour()*60 + at.Minute()
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// bypassesQuietHours reports whether an alert goes out even during quiet hours: in-stock alerts of critical SKUs
+// with --critical-ignore-quiet-hours
+func bypassesQuietHours(appConfig *config.AppConfig, sku, notificationType string) bool {
+	return appConfig.CriticalInQuietHours && notificationType == "in-stock" && isCriticalSKU(appConfig, sku)
+}
+
+// isPaused reports whether notifications are paused with --pause-until
+func isPaused(appConfig *config.AppConfig, now time.Time) bool {
+	return now.Before(appConfig.PauseUntil)
+}
+
+// formatQuietHours describes the quiet hours for logs and the startup message, e.g. 00:00-07:00 Asia/Kolkata
+func formatQuietHours(appConfig *config.AppConfig) string {
+	if appConfig.Timezone == nil || appConfig.QuietHoursStart == appConfig.QuietHoursEnd {
+		return "off"
+	}
+	return fmt.Sprintf("%02d:%02d-%02d:%02d %s", appConfig.QuietHoursStart/60, appConfig.QuietHoursStart%60,
+		appConfig.QuietHoursEnd/60, appConfig.QuietHoursEnd%60, appConfig.Timezone.String())
+}
+
+// Per-message delivery options derived from the notification type and SKU
+type messageOptions struct {
+	silent bool
+	pin    bool
+	// Forum topic in the primary chat, 0 for the general topic
+	threadID int
+	// Product photo sent with the message as its caption, empty for a text message
+	photoURL string
+	// The alert reports a change of stock status rather than a repeat, these also go to the broadcast channel
+	stockChange bool
+}
+
+func notificationOptions(appConfig *config.AppConfig, sku, notificationType string, stockChange bool) messageOptions {
+	options := messageOptions{
+		silent:      appConfig.SilentAlerts["all"] || appConfig.SilentAlerts[notificationType],
+		threadID:    topicThreadID(appConfig, sku),
+		stockChange: stockChange,
+	}
+
+	// Critical restocks always make a sound and stay pinned at the top of the chat. Only the restock itself is
+	// pinned and escalated, the alerts repeated while the product stays in stock are sent with normal priority.
+	if notificationType == "in-stock" && isCriticalSKU(appConfig, sku) {
+		options.silent = false
+		options.pin = stockChange
+	}
+	return options
+}
+
+// topicThreadID picks the forum topic for a SKU, falling back to its variant group and then the default topic
+func topicThreadID(appConfig *config.AppConfig, sku string) int {
+	if threadID, exists := appConfig.TopicThreadIDs[sku]; exists && sku != "" {
+		return threadID
+	}
+	if threadID, exists := appConfig.TopicThreadIDs[variantGroupKey(sku)+"_*"]; exists && sku != "" {
+		return threadID
+	}
+	return appConfig.TopicThreadIDs["default"]
+}
+
+// deliveryChatIDs returns the primary chat followed by every extra chat that receives copies
+func deliveryChatIDs(appConfig *config.AppConfig) []string {
+	return append([]string{appConfig.TelegramChatId}, appConfig.TelegramExtraChatIds...)
+}
+
+// activeDeliveryChatIDs returns the delivery chats, leaving out chats that recently blocked or removed the bot
+func activeDeliveryChatIDs(bot *Bot) []string {
+	activeChatIDs := []string{}
+	for _, chatID := range deliveryChatIDs(bot.appConfig) {
+		if isChatActive(bot, chatID) {
+			activeChatIDs = append(activeChatIDs, chatID)
+		}
+	}
+	return activeChatIDs
+}
+
+// isChatActive reports whether a chat may receive messages. Inactive chats get another try once the grace period has passed.
+func isChatActive(bot *Bot, chatID string) bool {
+	inactiveSince, isInactive := bot.inactiveChats[chatID]
+	if !isInactive {
+		return true
+	}
+	if time.Since(inactiveSince) < inactiveChatRetryAfter {
+		return false
+	}
+
+	log.Printf("Chat %s has been inactive for %v, trying to deliver to it again", chatID, inactiveChatRetryAfter)
+	delete(bot.inactiveChats, chatID)
+	return true
 }
 
-func sendTelegramNotification(message string, appConfig *config.AppConfig) error {
-	if isQuietHours(appConfig.Timezone) {
-		log.Printf("Telegram notification suppressed due to quiet hours (%d:00-%d:00 %s).", quietHourStart, quietHourEnd, appConfig.Timezone.String())
+func markChatInactive(bot *Bot, chatID string) {
+	bot.inactiveChats[chatID] = time.Now()
+	if chatID == bot.appConfig.TelegramChatId {
+		log.Printf("WARNING: The primary chat %s blocked or removed the bot. No alerts will reach it for the next %v.", chatID, inactiveChatRetryAfter)
+		return
+	}
+	log.Printf("Chat %s blocked or removed the bot, pausing deliveries to it for %v", chatID, inactiveChatRetryAfter)
+}
+
+// sendTelegramNotification delivers the message to every configured chat, returning the combined errors
+func sendTelegramNotification(message string, options messageOptions, appConfig *config.AppConfig) error {
+	if isQuietHours(appConfig) {
+		log.Printf("Telegram notification suppressed due to quiet hours (%s).", formatQuietHours(appConfig))
 		return nil
 	}
 
-	if appConfig.TelegramBotToken == "" || appConfig.TelegramChatId == "" {
+	var errs []error
+	for _, chatID := range deliveryChatIDs(appConfig) {
+		notifier := &telegramNotifier{chatID: chatID, appConfig: appConfig}
+		if err := notifier.Send(Notification{Message: message, options: options}); err != nil {
+			errs = append(errs, fmt.Errorf("chat %s: %w", chatID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// telegramNotifier delivers to one Telegram chat
+type telegramNotifier struct {
+	chatID    string
+	appConfig *config.AppConfig
+}
+
+func (n *telegramNotifier) Channel() string   { return "telegram" }
+func (n *telegramNotifier) Recipient() string { return n.chatID }
+
+func (n *telegramNotifier) Send(notification Notification) error {
+	return sendTelegramMessage(n.chatID, notification.Message, notification.options, n.appConfig)
+}
+
+func sendTelegramMessage(chatID, message string, options messageOptions, appConfig *config.AppConfig) error {
+	if appConfig.TelegramBotToken == "" || chatID == "" {
 		log.Println("Error: Attempted to send Telegram notification but token or chat ID is missing.")
 		return fmt.Errorf("telegram bot token or chat id is not configured")
 	}
 
-	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", appConfig.TelegramBotToken)
+	if options.photoURL != "" {
+		if len(splitMessage(message, appConfig.ParseMode, telegramCaptionLimit)) == 1 {
+			err := sendTelegramPhoto(chatID, message, options, appConfig)
+			if err == nil || isChatUnreachable(err) {
+				return err
+			}
+			// Telegram fetches the photo itself and fails when it can't, the alert still goes out as text
+			log.Printf("Warning: Failed to send photo to chat %s, sending the message as text: %v", chatID, err)
+		} else {
+			log.Printf("Message is longer than a photo caption, sending it as text")
+		}
+	}
 
-	payload := map[string]string{
-		"chat_id":                  appConfig.TelegramChatId,
-		"text":                     message,
-		"parse_mode":               "HTML",
-		"disable_web_page_preview": "false",
+	parts := splitMessage(message, appConfig.ParseMode, telegramMessageLimit)
+	if len(parts) > 1 {
+		log.Printf("Message is longer than %d characters, sending it in %d parts", telegramMessageLimit, len(parts))
 	}
 
+	for i, part := range parts {
+		payload := map[string]any{
+			"chat_id":                  chatID,
+			"text":                     renderMessage(part, appConfig.ParseMode),
+			"parse_mode":               appConfig.ParseMode,
+			"disable_web_page_preview": false,
+			"disable_notification":     options.silent,
+		}
+		// Topics only exist in the primary chat, extra chats always get the message in their main thread
+		if options.threadID != 0 && chatID == appConfig.TelegramChatId {
+			payload["message_thread_id"] = options.threadID
+		}
+		telegramThrottle.wait(chatID)
+		log.Printf("Attempting to send Telegram payload to chat ID %s...", chatID)
+
+		telegramResponse, err := callTelegramAPI("sendMessage", payload, appConfig)
+		if err != nil {
+			return err
+		}
+
+		// Pinning the first part keeps the start of the message at the top of the chat
+		if options.pin && i == 0 {
+			pinTelegramMessage(chatID, telegramResponse, appConfig)
+		}
+	}
+	return nil
+}
+
+// sendTelegramPhoto sends the product photo with the message as its caption
+func sendTelegramPhoto(chatID, message string, options messageOptions, appConfig *config.AppConfig) error {
+	payload := map[string]any{
+		"chat_id":              chatID,
+		"photo":                options.photoURL,
+		"caption":              renderMessage(message, appConfig.ParseMode),
+		"parse_mode":           appConfig.ParseMode,
+		"disable_notification": options.silent,
+	}
+	if options.threadID != 0 && chatID == appConfig.TelegramChatId {
+		payload["message_thread_id"] = options.threadID
+	}
+	telegramThrottle.wait(chatID)
+	log.Printf("Attempting to send Telegram photo to chat ID %s...", chatID)
+
+	telegramResponse, err := callTelegramAPI("sendPhoto", payload, appConfig)
+	if err != nil {
+		return err
+	}
+	if options.pin {
+		pinTelegramMessage(chatID, telegramResponse, appConfig)
+	}
+	return nil
+}
+
+// pinTelegramMessage pins a sent message, only logging failures since the bot may lack pin rights in the chat
+func pinTelegramMessage(chatID string, sendResponse map[string]any, appConfig *config.AppConfig) {
+	result, _ := sendResponse["result"].(map[string]any)
+	messageID, exists := result["message_id"].(float64)
+	if !exists {
+		log.Printf("Warning: Could not find message ID to pin in chat %s", chatID)
+		return
+	}
+
+	payload := map[string]any{
+		"chat_id":              chatID,
+		"message_id":           int64(messageID),
+		"disable_notification": false,
+	}
+	if _, err := callTelegramAPI("pinChatMessage", payload, appConfig); err != nil {
+		log.Printf("Warning: Failed to pin message %d in chat %s: %v", int64(messageID), chatID, err)
+		return
+	}
+	log.Printf("Pinned message %d in chat %s", int64(messageID), chatID)
+}
+
+// Error returned when the Bot API answers with a non-OK HTTP status
+type telegramAPIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *telegramAPIError) Error() string {
+	return fmt.Sprintf("telegram api returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// isChatUnreachable reports whether Telegram refused delivery because the bot was blocked, kicked or the user deleted their account
+func isChatUnreachable(err error) bool {
+	var apiErr *telegramAPIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden
+}
+
+// parseRetryAfter reads how long Telegram asks to wait from an error response, 0 when it doesn't say
+func parseRetryAfter(body []byte) time.Duration {
+	var response struct {
+		Parameters struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return 0
+	}
+	return time.Duration(response.Parameters.RetryAfter) * time.Second
+}
+
+// callTelegramAPI posts a JSON payload to a Bot API method and returns the decoded response
+func callTelegramAPI(method string, payload map[string]any, appConfig *config.AppConfig) (map[string]any, error) {
+	apiURL := fmt.Sprintf("https://api.telegram.org/bot%s/%s", appConfig.TelegramBotToken, method)
+
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
 		log.Printf("Error marshalling telegram payload: %v", err)
-		return fmt.Errorf("error marshalling telegram payload: %w", err)
+		return nil, fmt.Errorf("error marshalling telegram payload: %w", err)
 	}
-	log.Printf("Attempting to send Telegram payload to chat ID %s...", appConfig.TelegramChatId)
 
 	client := &http.Client{Timeout: 15 * time.Second}
 	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonPayload))
 	if err != nil {
 		log.Printf("Error creating Telegram request: %v", err)
-		return fmt.Errorf("error creating request: %w", err)
+		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "AmulStockNotifier/1.2")
@@ -77,21 +331,25 @@ func sendTelegramNotification(message string, appConfig *config.AppConfig) error
 	resp, err := client.Do(req)
 	if err != nil {
 		log.Printf("Error sending request to Telegram API: %v", err)
-		return fmt.Errorf("error sending request to telegram api: %w", err)
+		return nil, fmt.Errorf("error sending request to telegram api: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, readErr := io.ReadAll(resp.Body)
 	if readErr != nil {
 		log.Printf("Error reading Telegram response body (Status: %s): %v", resp.Status, readErr)
-		return fmt.Errorf("error reading telegram response body (status %d): %w", resp.StatusCode, readErr)
+		return nil, fmt.Errorf("error reading telegram response body (status %d): %w", resp.StatusCode, readErr)
 	}
 
 	log.Printf("Telegram API response Status: %s", resp.Status)
 	if resp.StatusCode != http.StatusOK {
 		log.Printf("Telegram API response Body (Error): %s", string(body))
 		log.Printf("Error: Telegram API returned non-OK status: %d", resp.StatusCode)
-		return fmt.Errorf("telegram api returned status %d: %s", resp.StatusCode, string(body))
+		if retryAfter := parseRetryAfter(body); resp.StatusCode == http.StatusTooManyRequests && retryAfter > 0 {
+			log.Printf("Telegram rate limit hit, pausing sends for %v", retryAfter)
+			telegramThrottle.pause(retryAfter)
+		}
+		return nil, &telegramAPIError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var telegramResponse map[string]any
@@ -100,83 +358,197 @@ func sendTelegramNotification(message string, appConfig *config.AppConfig) error
 	} else {
 		if ok, exists := telegramResponse["ok"].(bool); !exists || !ok {
 			log.Printf("Error: Telegram API status OK, but response indicates failure: %s", string(body))
-			return fmt.Errorf("telegram api reported failure despite 200 OK: %s", string(body))
+			return nil, fmt.Errorf("telegram api reported failure despite 200 OK: %s", string(body))
 		}
 	}
 
 	log.Printf("Telegram request successful (Status: %s)", resp.Status)
-	return nil
+	return telegramResponse, nil
 }
 
 func SendInitialStockNotifications(bot *Bot) {
+	if bot.stateRestored {
+		log.Println("Skipping the initial stock alert, stock state was restored from the previous run.")
+		return
+	}
 	log.Println("Checking for products already in stock at startup...")
 
-	inStockMessages := []string{}
+	inStockProducts := []amulclient.Product{}
 
-	for sku := range bot.appConfig.MonitoredSKUsMap {
+	for sku := range trackedSKUs(bot) {
 		if inStock, exists := bot.productStockState[sku]; exists && inStock {
 			prodInfo, detailsExist := bot.productDetails[sku]
-			name := "Unknown Product"
-			alias := ""
-			inventory := 0
-			if detailsExist {
-				name = prodInfo.Name
-				alias = prodInfo.Alias
-				inventory = prodInfo.InventoryQuantity
-			} else {
+			if !detailsExist {
 				log.Printf("Warning: Details missing for initially in-stock SKU %s", sku)
+				prodInfo = amulclient.Product{SKU: sku, Name: "Unknown Product"}
 			}
 
-			log.Printf("Found monitored product already in stock at startup: %s (SKU: %s)", name, sku)
-
-			link := ""
-			if alias != "" {
-				link = fmt.Sprintf("\n🔗 <a href=\"%s%s\">View on Amul Shop</a>", productBaseURL, alias)
-			}
+			log.Printf("Found monitored product already in stock at startup: %s (SKU: %s)", prodInfo.Name, sku)
+			inStockProducts = append(inStockProducts, prodInfo)
+		}
+	}
 
-			message := fmt.Sprintf("• <b>%s</b> (SKU: %s) - Qty: %d %s", name, sku, inventory, link)
+	inStockMessages := []string{}
+	for _, group := range groupVariants(inStockProducts) {
+		if len(group.Variants) == 1 {
+			product := group.Variants[0]
+			message := fmt.Sprintf("• <b>%s</b> (SKU: %s) - Qty: %d - %s%s %s",
+				productLabel(bot.appConfig, product.Name, product.SKU), product.SKU, product.InventoryQuantity, formatPriceDetails(product),
+				formatSKUNote(bot.appConfig, product.SKU), initialStockLink(product))
 			inStockMessages = append(inStockMessages, message)
+			continue
+		}
+
+		// Several pack sizes of the same product are listed together under one entry
+		variantLines := []string{fmt.Sprintf("• <b>%s</b> (%d pack sizes)", productLabel(bot.appConfig, group.BaseName, group.Variants[0].SKU), len(group.Variants))}
+		for _, product := range group.Variants {
+			_, packSize := splitPackSize(product.Name)
+			if packSize == "" {
+				packSize = product.Name
+			}
+			packSize = escapeHTML(packSize)
+			variantLines = append(variantLines, fmt.Sprintf("   ◦ %s (SKU: %s) - Qty: %d - %s%s %s",
+				packSize, product.SKU, product.InventoryQuantity, formatPriceDetails(product),
+				formatSKUNote(bot.appConfig, product.SKU), initialStockLink(product)))
 		}
+		inStockMessages = append(inStockMessages, strings.Join(variantLines, "\n"))
 	}
 
 	if len(inStockMessages) > 0 {
 		fullMessage := "<b>Initial Stock Alert!</b>\n\nThese monitored products are currently IN STOCK:\n" +
 			strings.Join(inStockMessages, "\n")
 
-		err := sendTelegramNotification(fullMessage, bot.appConfig)
-		if err != nil {
-			if !isQuietHours(bot.appConfig.Timezone) {
-				log.Printf("Error sending initial stock notification: %v", err)
-			}
-		} else {
-			log.Println("Initial stock notification sent (or suppressed).")
-		}
+		sendNotificationWithRetry(bot, fullMessage, "", "initial-stock")
 	} else {
 		log.Println("No monitored products found in stock at startup.")
 	}
 }
 
-func sendNotificationWithRetry(appConfig *config.AppConfig, message, sku, notificationType string) {
-	if isQuietHours(appConfig.Timezone) {
+func initialStockLink(product amulclient.Product) string {
+	if product.Alias == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n🔗 <a href=\"%s%s\">View on Amul Shop</a>", productBaseURL, product.Alias)
+}
+
+// Broadcast progress is logged after this many chats, and after the last one
+const broadcastProgressEvery = 25
+
+func sendNotificationWithRetry(bot *Bot, message, sku, notificationType string) {
+	sendNotification(bot, message, sku, notificationType, false)
+}
+
+// sendStockChangeNotification sends an alert for a product whose stock status just changed
+func sendStockChangeNotification(bot *Bot, message, sku, notificationType string) {
+	sendNotification(bot, message, sku, notificationType, true)
+}
+
+func sendNotification(bot *Bot, message, sku, notificationType string, stockChange bool) {
+	if isPaused(bot.appConfig, time.Now()) {
+		log.Printf("Notification (%s) for SKU %s suppressed, notifications are paused until %s.", notificationType, sku, bot.appConfig.PauseUntil.Format(time.RFC3339))
+		return
+	}
+	if isQuietHours(bot.appConfig) && !bypassesQuietHours(bot.appConfig, sku, notificationType) {
 		log.Printf("Notification (%s) for SKU %s suppressed due to quiet hours.", notificationType, sku)
+		recordSuppressedAlert(bot, sku, notificationType, time.Now())
+		return
+	}
+	if isDuplicateAlert(bot, sku, notificationType, time.Now()) {
+		log.Printf("Notification (%s) for SKU %s suppressed, the same alert was sent within the last %v.", notificationType, sku, bot.appConfig.DedupeWindow)
 		return
 	}
 
-	var notifErr error
-	for attempts := range 3 {
-		notifErr = sendTelegramNotification(message, appConfig)
-		if notifErr == nil {
-			log.Printf("Telegram notification (%s) sent successfully for %s (Attempt %d).", notificationType, sku, attempts+1)
-			return
-		}
+	sendStart := time.Now()
+	defer func() { bot.cycleNotifyDuration += time.Since(sendStart) }()
 
-		log.Printf("Attempt %d: Error sending Telegram notification (%s) for %s: %v",
-			attempts+1, notificationType, sku, notifErr)
+	options := notificationOptions(bot.appConfig, sku, notificationType, stockChange)
+	if notificationType == "in-stock" && bot.appConfig.AlertPhotos {
+		options.photoURL = bot.productDetails[sku].Image.URL()
+	}
+	dispatch(bot, Notification{
+		Message: message,
+		SKU:     sku,
+		Type:    notificationType,
+		options: options,
+	})
+}
+
+// RetryPendingNotifications re-drives dead letters when asked to, then re-sends outbox entries left pending by a
+// previous run
+func RetryPendingNotifications(bot *Bot) {
+	if bot.outbox == nil {
+		return
+	}
+
+	if bot.appConfig.RedriveDeadLetters {
+		log.Printf("Re-driving %d dead-lettered notifications", bot.outbox.Redrive())
+	} else if deadLetters := len(bot.outbox.DeadLetters()); deadLetters > 0 {
+		log.Printf("Warning: %d notifications are in the dead-letter log, inspect them with --list-dead-letters", deadLetters)
+	}
+
+	log.Printf("Outbox delivery stats: %v, %d pending from a previous run", bot.outbox.Stats(), len(bot.outbox.Pending()))
+	RetryDueNotifications(bot)
+}
 
-		if attempts < 2 {
-			time.Sleep(2 * time.Second)
+// RetryDueNotifications re-sends the pending outbox entries whose next retry is due, skipping stale ones.
+// It runs every outbox.RetryInterval, independently of the check interval.
+func RetryDueNotifications(bot *Bot) {
+	retryDueNotifications(bot, time.Now())
+}
+
+func retryDueNotifications(bot *Bot, now time.Time) {
+	due := bot.outbox.Due(now)
+	if len(due) == 0 {
+		return
+	}
+	if isPaused(bot.appConfig, now) {
+		log.Printf("Retry of %d pending notifications postponed, notifications are paused.", len(due))
+		return
+	}
+	if isQuietHours(bot.appConfig) {
+		log.Printf("Retry of %d pending notifications postponed due to quiet hours.", len(due))
+		return
+	}
+
+	for _, entry := range due {
+		notifier := findNotifier(bot, entry.Channel, entry.ChatID)
+		if notifier == nil {
+			log.Printf("Outbox entry %d is for %s recipient %s that no longer receives notifications, moving it to dead letters", entry.ID, entry.Channel, entry.ChatID)
+			bot.outbox.MarkDeadLetter(entry.ID)
+			continue
+		}
+		if maxAge := outbox.MaxRetryAge(); now.Sub(entry.QueuedAt()) > maxAge {
+			log.Printf("Outbox entry %d (%s for %s) is older than %v, not retrying", entry.ID, entry.NotificationType, entry.SKU, maxAge)
+			bot.outbox.MarkExpired(entry.ID)
+			continue
 		}
 
+		log.Printf("Retrying pending outbox entry %d (%s for %s)", entry.ID, entry.NotificationType, entry.SKU)
+		deliverWithRetry(bot, entry.ID, notifier, Notification{
+			Message: entry.Message,
+			SKU:     entry.SKU,
+			Type:    entry.NotificationType,
+			options: messageOptions{silent: entry.Silent, pin: entry.Pin, threadID: entry.ThreadID, photoURL: entry.PhotoURL},
+		})
+	}
+}
+
+// PrintDeadLetters writes every dead-lettered notification with its failure reason to stdout
+func PrintDeadLetters(appConfig *config.AppConfig) error {
+	notificationOutbox, err := outbox.OpenReadOnly(appConfig.OutboxFile, appConfig.StoreEncryptionKey)
+	if err != nil {
+		return err
 	}
-	log.Printf("FAILED to send Telegram notification (%s) after 3 attempts for %s", notificationType, sku)
+
+	deadLetters := notificationOutbox.DeadLetters()
+	fmt.Printf("%d dead-lettered notifications in %s\n", len(deadLetters), appConfig.OutboxFile)
+	for _, entry := range deadLetters {
+		fmt.Printf("\n#%d %s to %s chat %s (SKU: %s)\n  queued: %s, dead-lettered: %s after %d attempts\n  reason: %s\n",
+			entry.ID, entry.NotificationType, entry.Channel, entry.ChatID, entry.SKU,
+			entry.QueuedAt().Format(time.RFC3339), entry.DeadLetteredAt.Format(time.RFC3339), entry.Attempts, entry.LastError)
+	}
+	if len(deadLetters) > 0 {
+		fmt.Println("\nRun with --redrive-dead-letters to retry them.")
+	}
+	return nil
 }
@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"amul-notifier/internal/config"
+	"amul-notifier/internal/outbox"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuietHours(t *testing.T) {
+	at := func(hour, minute int) time.Time { return time.Date(2025, 5, 1, hour, minute, 0, 0, time.UTC) }
+
+	t.Run("Quiet hours within a day", func(t *testing.T) {
+		appConfig := &config.AppConfig{QuietHoursStart: 0, QuietHoursEnd: 7 * 60, Timezone: time.UTC}
+		assert.True(t, isQuietTime(appConfig, at(0, 0)))
+		assert.True(t, isQuietTime(appConfig, at(6, 59)))
+		assert.False(t, isQuietTime(appConfig, at(7, 0)))
+		assert.Equal(t, "00:00-07:00 UTC", formatQuietHours(appConfig))
+	})
+
+	t.Run("Quiet hours past midnight", func(t *testing.T) {
+		appConfig := &config.AppConfig{QuietHoursStart: 22*60 + 30, QuietHoursEnd: 6 * 60, Timezone: time.UTC}
+		assert.False(t, isQuietTime(appConfig, at(22, 29)))
+		assert.True(t, isQuietTime(appConfig, at(22, 30)))
+		assert.True(t, isQuietTime(appConfig, at(3, 0)))
+		assert.False(t, isQuietTime(appConfig, at(6, 0)))
+	})
+
+	t.Run("Critical restocks bypass quiet hours when enabled", func(t *testing.T) {
+		appConfig := &config.AppConfig{CriticalSKUsMap: map[string]bool{"WPCCP03_01": true, "HPPCP01_*": true}}
+		assert.False(t, bypassesQuietHours(appConfig, "WPCCP03_01", "in-stock"))
+
+		appConfig.CriticalInQuietHours = true
+		assert.True(t, bypassesQuietHours(appConfig, "WPCCP03_01", "in-stock"))
+		assert.True(t, bypassesQuietHours(appConfig, "HPPCP01_24", "in-stock"))
+		assert.False(t, bypassesQuietHours(appConfig, "WPCCP03_01", "out-of-stock"))
+		assert.False(t, bypassesQuietHours(appConfig, "LASCP40_30", "in-stock"))
+	})
+
+	t.Run("Pause notifications until a time", func(t *testing.T) {
+		appConfig := &config.AppConfig{PauseUntil: at(9, 0)}
+		assert.True(t, isPaused(appConfig, at(8, 59)))
+		assert.False(t, isPaused(appConfig, at(9, 0)))
+		assert.False(t, isPaused(&config.AppConfig{}, at(9, 0)))
+	})
+
+	t.Run("Skip the startup message while paused", func(t *testing.T) {
+		// Without a bot token, sending would fail outside quiet hours
+		assert.NoError(t, StartupTestNotification(&config.AppConfig{PauseUntil: time.Now().Add(time.Hour)}))
+	})
+
+	t.Run("Hold outbox retries while paused", func(t *testing.T) {
+		notificationOutbox, err := outbox.Open(filepath.Join(t.TempDir(), "outbox.json"), nil)
+		assert.NoError(t, err)
+		pushover := &fakeNotifier{channel: "pushover"}
+		bot := &Bot{outbox: notificationOutbox, notifiers: []Notifier{pushover}, appConfig: &config.AppConfig{PauseUntil: time.Now().Add(time.Hour)}}
+		bot.outbox.Add(outbox.Entry{Channel: "pushover", SKU: "LASCP40_30", NotificationType: "in-stock"})
+
+		RetryDueNotifications(bot)
+		assert.Empty(t, pushover.sent)
+		assert.Len(t, bot.outbox.Pending(), 1)
+
+		bot.appConfig.PauseUntil = time.Time{}
+		RetryDueNotifications(bot)
+		assert.Len(t, pushover.sent, 1)
+		assert.Empty(t, bot.outbox.Pending())
+	})
+
+	t.Run("Quiet hours turned off", func(t *testing.T) {
+		appConfig := &config.AppConfig{Timezone: time.UTC}
+		assert.False(t, isQuietTime(appConfig, at(3, 0)))
+		assert.Equal(t, "off", formatQuietHours(appConfig))
+	})
+}
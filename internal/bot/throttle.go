@@ -0,0 +1,73 @@
+package bot
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// Telegram allows about 30 messages per second overall and 1 per second in a single chat
+	telegramGlobalSendInterval = time.Second / 30
+	telegramChatSendInterval   = time.Second
+)
+
+// Paces every message sent with the bot token, Telegram's limits apply to the token and not to a single broadcast
+var telegramThrottle = newSendThrottle(telegramGlobalSendInterval, telegramChatSendInterval)
+
+// sendThrottle spaces out sends to stay within an overall rate and a per-chat rate
+type sendThrottle struct {
+	mu             sync.Mutex
+	globalInterval time.Duration
+	chatInterval   time.Duration
+	nextSend       time.Time
+	nextChatSend   map[string]time.Time
+	now            func() time.Time
+	sleep          func(time.Duration)
+}
+
+func newSendThrottle(globalInterval, chatInterval time.Duration) *sendThrottle {
+	return &sendThrottle{
+		globalInterval: globalInterval,
+		chatInterval:   chatInterval,
+		nextChatSend:   make(map[string]time.Time),
+		now:            time.Now,
+		sleep:          time.Sleep,
+	}
+}
+
+// pause holds back every send for a while, after Telegram answered 429 Too Many Requests
+func (t *sendThrottle) pause(duration time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if resumeAt := t.now().Add(duration); resumeAt.After(t.nextSend) {
+		t.nextSend = resumeAt
+	}
+}
+
+// wait blocks until a message may be sent to the chat and reserves that slot
+func (t *sendThrottle) wait(chatID string) {
+	t.mu.Lock()
+	now := t.now()
+	sendAt := now
+	if t.nextSend.After(sendAt) {
+		sendAt = t.nextSend
+	}
+	if nextChatSend := t.nextChatSend[chatID]; nextChatSend.After(sendAt) {
+		sendAt = nextChatSend
+	}
+	t.nextSend = sendAt.Add(t.globalInterval)
+	t.nextChatSend[chatID] = sendAt.Add(t.chatInterval)
+
+	// Chats whose slot has passed need no entry, which keeps the map small after large broadcasts
+	for chatID, nextChatSend := range t.nextChatSend {
+		if !nextChatSend.After(now) {
+			delete(t.nextChatSend, chatID)
+		}
+	}
+	t.mu.Unlock()
+
+	if delay := sendAt.Sub(now); delay > 0 {
+		t.sleep(delay)
+	}
+}
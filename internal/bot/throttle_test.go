@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendThrottle(t *testing.T) {
+	t.Run("Space sends overall and per chat", func(t *testing.T) {
+		now := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+		throttle := newSendThrottle(100*time.Millisecond, time.Second)
+		throttle.now = func() time.Time { return now }
+		delays := []time.Duration{}
+		throttle.sleep = func(delay time.Duration) { delays = append(delays, delay) }
+
+		throttle.wait("a")
+		throttle.wait("b")
+		throttle.wait("c")
+		throttle.wait("a")
+		assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, time.Second}, delays)
+
+		// Once the clock has caught up, no waiting is needed
+		now = now.Add(5 * time.Second)
+		throttle.wait("a")
+		assert.Equal(t, 3, len(delays))
+	})
+
+	t.Run("Read retry_after from rate limit responses", func(t *testing.T) {
+		assert.Equal(t, 35*time.Second, parseRetryAfter([]byte(`{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 35","parameters":{"retry_after":35}}`)))
+		assert.Equal(t, time.Duration(0), parseRetryAfter([]byte(`{"ok":false,"error_code":400,"description":"Bad Request"}`)))
+	})
+
+	t.Run("Pause every send after a rate limit", func(t *testing.T) {
+		now := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+		throttle := newSendThrottle(100*time.Millisecond, time.Second)
+		throttle.now = func() time.Time { return now }
+		delays := []time.Duration{}
+		throttle.sleep = func(delay time.Duration) { delays = append(delays, delay) }
+
+		throttle.pause(7 * time.Second)
+		throttle.wait("a")
+		throttle.wait("b")
+		assert.Equal(t, []time.Duration{7 * time.Second, 7*time.Second + 100*time.Millisecond}, delays)
+	})
+}
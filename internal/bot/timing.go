@@ -0,0 +1,53 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Phases of one stock check cycle and how long each took, in the order they ran
+type cycleTiming struct {
+	startedAt time.Time
+	phases    []phaseDuration
+}
+
+type phaseDuration struct {
+	name     string
+	duration time.Duration
+}
+
+func newCycleTiming() *cycleTiming {
+	return &cycleTiming{startedAt: time.Now()}
+}
+
+func (c *cycleTiming) add(name string, duration time.Duration) {
+	c.phases = append(c.phases, phaseDuration{name: name, duration: duration})
+}
+
+// summary renders the phases as key=value pairs followed by the total, e.g. "fetch=812ms notify=1.2s total=2.01s"
+func (c *cycleTiming) summary(total time.Duration) string {
+	pairs := make([]string, 0, len(c.phases)+1)
+	for _, phase := range c.phases {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", phase.name, phase.duration.Round(time.Millisecond)))
+	}
+	pairs = append(pairs, fmt.Sprintf("total=%v", total.Round(time.Millisecond)))
+	return strings.Join(pairs, " ")
+}
+
+// reportCycleTiming logs how long each phase of a check took, alerting when the whole check was slow
+func reportCycleTiming(bot *Bot, timing *cycleTiming) {
+	total := time.Since(timing.startedAt)
+	summary := timing.summary(total)
+	log.Printf("Check cycle timing: %s", summary)
+
+	threshold := bot.appConfig.SlowCheckThreshold
+	if threshold <= 0 || total <= threshold {
+		return
+	}
+	log.Printf("WARNING: Check cycle took %v, above the slow-check threshold of %v", total.Round(time.Millisecond), threshold)
+	message := fmt.Sprintf("🐢 <b>Slow stock check</b>\n\nThe last check took %v, above the %v threshold. The Amul API or the network may be slow.\nPhases: %s",
+		total.Round(time.Millisecond), threshold, escapeHTML(summary))
+	sendNotificationWithRetry(bot, message, "", "slow-check")
+}
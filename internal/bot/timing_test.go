@@ -0,0 +1,17 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCycleTiming(t *testing.T) {
+	t.Run("Summarize phases in order", func(t *testing.T) {
+		timing := newCycleTiming()
+		timing.add("fetch", 812*time.Millisecond+300*time.Microsecond)
+		timing.add("notify", 1200*time.Millisecond)
+		assert.Equal(t, "fetch=812ms notify=1.2s total=2.1s", timing.summary(2100*time.Millisecond))
+	})
+}
@@ -0,0 +1,61 @@
+package bot
+
+import (
+	"amul-notifier/pkg/amulclient"
+	"slices"
+	"strings"
+)
+
+// Pack sizes are encoded as the suffix after the last underscore in the SKU (e.g. HPPCP01_02, HPPCP01_24)
+const variantSeparator = "_"
+
+// A product sold in one or more pack sizes
+type variantGroup struct {
+	Key      string
+	BaseName string
+	Variants []amulclient.Product
+}
+
+// variantGroupKey returns the part of the SKU shared by all pack sizes of a product
+func variantGroupKey(sku string) string {
+	if idx := strings.LastIndex(sku, variantSeparator); idx > 0 {
+		return sku[:idx]
+	}
+	return sku
+}
+
+// splitPackSize separates the trailing "Pack of N" segment from a product name, if present
+func splitPackSize(name string) (string, string) {
+	idx := strings.LastIndex(name, "|")
+	if idx < 0 {
+		return name, ""
+	}
+	packSize := strings.TrimSpace(name[idx+1:])
+	if !strings.HasPrefix(strings.ToLower(packSize), "pack of") {
+		return name, ""
+	}
+	return strings.TrimSpace(name[:idx]), packSize
+}
+
+// groupVariants groups products sharing a variant key, sorted by key and then by SKU
+func groupVariants(products []amulclient.Product) []variantGroup {
+	groupsByKey := make(map[string]*variantGroup)
+	for _, product := range products {
+		key := variantGroupKey(product.SKU)
+		group, exists := groupsByKey[key]
+		if !exists {
+			baseName, _ := splitPackSize(product.Name)
+			group = &variantGroup{Key: key, BaseName: baseName}
+			groupsByKey[key] = group
+		}
+		group.Variants = append(group.Variants, product)
+	}
+
+	groups := make([]variantGroup, 0, len(groupsByKey))
+	for _, group := range groupsByKey {
+		slices.SortFunc(group.Variants, func(a, b amulclient.Product) int { return strings.Compare(a.SKU, b.SKU) })
+		groups = append(groups, *group)
+	}
+	slices.SortFunc(groups, func(a, b variantGroup) int { return strings.Compare(a.Key, b.Key) })
+	return groups
+}
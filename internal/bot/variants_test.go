@@ -0,0 +1,39 @@
+package bot
+
+import (
+	"amul-notifier/pkg/amulclient"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVariantGrouping(t *testing.T) {
+	t.Run("Variant key from SKU", func(t *testing.T) {
+		assert.Equal(t, "HPPCP01", variantGroupKey("HPPCP01_24"))
+		assert.Equal(t, "NOSUFFIX", variantGroupKey("NOSUFFIX"))
+	})
+
+	t.Run("Split pack size from name", func(t *testing.T) {
+		baseName, packSize := splitPackSize("Amul High Protein Paneer, 400 g | Pack of 24")
+		assert.Equal(t, "Amul High Protein Paneer, 400 g", baseName)
+		assert.Equal(t, "Pack of 24", packSize)
+
+		baseName, packSize = splitPackSize("Amul Kool Protein Milkshake | Chocolate, 180 mL")
+		assert.Equal(t, "Amul Kool Protein Milkshake | Chocolate, 180 mL", baseName)
+		assert.Equal(t, "", packSize)
+	})
+
+	t.Run("Group pack sizes of the same product", func(t *testing.T) {
+		groups := groupVariants([]amulclient.Product{
+			{SKU: "HPPCP01_24", Name: "Amul High Protein Paneer, 400 g | Pack of 24"},
+			{SKU: "LASCP40_30", Name: "Amul High Protein Rose Lassi, 200 mL | Pack of 30"},
+			{SKU: "HPPCP01_02", Name: "Amul High Protein Paneer, 400 g | Pack of 2"},
+		})
+		assert.Equal(t, 2, len(groups))
+		assert.Equal(t, "HPPCP01", groups[0].Key)
+		assert.Equal(t, "Amul High Protein Paneer, 400 g", groups[0].BaseName)
+		assert.Equal(t, "HPPCP01_02", groups[0].Variants[0].SKU)
+		assert.Equal(t, "HPPCP01_24", groups[0].Variants[1].SKU)
+		assert.Equal(t, "LASCP40", groups[1].Key)
+	})
+}
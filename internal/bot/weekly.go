@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"amul-notifier/internal/history"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Period covered by the weekly digest
+const weeklyDigestLookback = 7 * 24 * time.Hour
+
+// SendWeeklyDigest sends the weekly digest with the first check at or after the configured weekday and time
+func SendWeeklyDigest(bot *Bot) {
+	if bot.appConfig.WeeklyDigestTime == "" {
+		return
+	}
+
+	now := time.Now()
+	if bot.appConfig.Timezone != nil {
+		now = now.In(bot.appConfig.Timezone)
+	}
+	today := now.Format(time.DateOnly)
+	if now.Weekday() != bot.appConfig.WeeklyDigestDay || bot.lastWeeklyDigestDate == today || now.Format("15:04") < bot.appConfig.WeeklyDigestTime {
+		return
+	}
+	if isQuietHours(bot.appConfig) {
+		log.Printf("Weekly digest postponed due to quiet hours.")
+		return
+	}
+
+	bot.lastWeeklyDigestDate = today
+	saveState(bot)
+	sendNotificationWithRetry(bot, buildWeeklyDigest(bot, now), "", "weekly-digest")
+}
+
+// inStockDuration adds up the time between consecutive records while a SKU was in stock
+func inStockDuration(records []history.Record) time.Duration {
+	var total time.Duration
+	for i := 1; i < len(records); i++ {
+		if records[i-1].Available {
+			total += records[i].At.Sub(records[i-1].At)
+		}
+	}
+	return total
+}
+
+// buildWeeklyDigest summarizes the restocks, time in stock and price changes of the last week from the history
+func buildWeeklyDigest(bot *Bot, now time.Time) string {
+	skus := []string{}
+	for sku := range trackedSKUs(bot) {
+		skus = append(skus, sku)
+	}
+	slices.Sort(skus)
+
+	var available, unavailable, priceChanges []string
+	for _, sku := range skus {
+		name := sku
+		if product, exists := bot.productDetails[sku]; exists {
+			name = product.Name
+		}
+		label := productLabel(bot.appConfig, name, sku)
+
+		records := bot.history.Query(sku, now.Add(-weeklyDigestLookback), now)
+		restocks := 0
+		for _, change := range stockChanges(records) {
+			if change.inStock {
+				restocks++
+			}
+		}
+		inStock := inStockDuration(records)
+
+		switch {
+		case restocks > 0:
+			available = append(available, fmt.Sprintf("✅ %s: restocked %s, in stock for %s", label, formatTimes(restocks), formatDuration(inStock)))
+		case inStock > 0:
+			available = append(available, fmt.Sprintf("✅ %s: in stock for %s", label, formatDuration(inStock)))
+		default:
+			unavailable = append(unavailable, "❌ "+label)
+		}
+		if priceChange := formatPriceChange(label, records); priceChange != "" {
+			priceChanges = append(priceChanges, priceChange)
+		}
+	}
+
+	var digest strings.Builder
+	digest.WriteString(fmt.Sprintf("🗓️ <b>Weekly Digest</b> (%s to %s)\n",
+		now.Add(-weeklyDigestLookback).Format("2 Jan"), now.Format("2 Jan")))
+	writeBriefingSection(&digest, "In stock this week", available)
+	writeBriefingSection(&digest, "Not in stock this week", unavailable)
+	writeBriefingSection(&digest, "Price changes", priceChanges)
+	return digest.String()
+}
+
+func formatTimes(count int) string {
+	if count == 1 {
+		return "once"
+	}
+	return fmt.Sprintf("%d times", count)
+}
@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"amul-notifier/internal/config"
+	"amul-notifier/internal/history"
+	"amul-notifier/pkg/amulclient"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeeklyDigest(t *testing.T) {
+	t.Run("Summarize restocks, time in stock and prices", func(t *testing.T) {
+		store, err := history.Open(filepath.Join(t.TempDir(), "history.jsonl"), nil)
+		assert.NoError(t, err)
+		now := time.Date(2025, 5, 4, 18, 0, 0, 0, time.UTC)
+		day := 24 * time.Hour
+		assert.NoError(t, store.Append([]history.Record{
+			{At: now.Add(-6 * day), SKU: "LASCP40_30", Available: false, Price: 450},
+			{At: now.Add(-5 * day), SKU: "LASCP40_30", Available: true, Price: 450},
+			{At: now.Add(-5*day + 3*time.Hour), SKU: "LASCP40_30", Available: false, Price: 450},
+			{At: now.Add(-2 * day), SKU: "LASCP40_30", Available: true, Price: 400},
+			{At: now.Add(-2*day + 90*time.Minute), SKU: "LASCP40_30", Available: false, Price: 400},
+			{At: now.Add(-6 * day), SKU: "WPCCP01_01", Available: true},
+			{At: now.Add(-4 * day), SKU: "WPCCP01_01", Available: true},
+			{At: now.Add(-3 * day), SKU: "HPPCP01_24", Available: false},
+		}))
+
+		bot := &Bot{
+			productStockState: map[string]bool{"LASCP40_30": false, "HPPCP01_24": false, "WPCCP01_01": true},
+			productDetails: map[string]amulclient.Product{
+				"LASCP40_30": {SKU: "LASCP40_30", Name: "Rose Lassi"},
+			},
+			history: store,
+			appConfig: &config.AppConfig{MonitoredSKUsMap: map[string]bool{
+				"LASCP40_30": true, "HPPCP01_24": true, "WPCCP01_01": true,
+			}},
+		}
+
+		assert.Equal(t, "🗓️ <b>Weekly Digest</b> (27 Apr to 4 May)\n"+
+			"\n<b>In stock this week</b>\n✅ Rose Lassi: restocked 2 times, in stock for 4 hours 30 minutes\n✅ WPCCP01_01: in stock for 2 days\n"+
+			"\n<b>Not in stock this week</b>\n❌ HPPCP01_24\n"+
+			"\n<b>Price changes</b>\n💰 Rose Lassi: ₹450 → ₹400\n", buildWeeklyDigest(bot, now))
+	})
+}
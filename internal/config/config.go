@@ -1,32 +1,151 @@
 package config
 
 import (
+	"amul-notifier/internal/storage"
+	"cmp"
 	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"net/url"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Amul store checked when no store is configured, or when the configured one is rejected
+const DefaultStore = "gujarat"
+
+// Quiet hours used when neither --quiet-hours nor the QUIET_HOURS environment variables are set
+const defaultQuietHours = "00:00-07:00"
+
+// Monitored entries ending with this suffix match every pack size of a product (e.g. HPPCP01_*)
+const variantWildcardSuffix = "_*"
+
 type AppConfig struct {
 	CheckInterval    time.Duration
 	Timezone         *time.Location
 	TelegramBotToken string
 	TelegramChatId   string
-	MonitoredSKUsMap map[string]bool
+	// Telegram parse mode used for every message, HTML or MarkdownV2
+	ParseMode string
+	// Additional chats that receive a copy of every notification
+	TelegramExtraChatIds []string
+	// Channel receiving only stock changes (in-stock and out-of-stock alerts), for a public community channel
+	TelegramChannelID string
+	MonitoredSKUsMap  map[string]bool
+	// SKU prefix -> true, for products monitored in any pack size
+	MonitoredVariantsMap map[string]bool
+	// Friendly alias -> SKU (or SKU prefix wildcard)
+	SKUAliases map[string]string
+	// SKU (or SKU prefix wildcard) -> personal note shown in alerts
+	SKUNotes    map[string]string
+	OfferAlerts bool
+	// Send in-stock alerts as product photos with the alert as caption
+	AlertPhotos bool
+	// Address for the HTTP server exposing /metrics, disabled when empty
+	HTTPAddr string
+	// Bearer token required by every HTTP endpoint, open to anyone when empty
+	HTTPToken string
+	// JSON Lines file recording stock and price history, disabled when empty
+	HistoryFile string
+	// JSON file recording every outgoing notification, disabled when empty
+	OutboxFile string
+	// AES-256 key used to encrypt stored files, nil to store them as plaintext
+	StoreEncryptionKey []byte
+	// Print the dead-letter log and exit
+	ListDeadLetters bool
+	// Move dead letters back to the pending queue on startup
+	RedriveDeadLetters bool
+	// Notification types (or "all") delivered without sound
+	SilentAlerts map[string]bool
+	// SKUs (or SKU prefix wildcards) whose restocks are pinned and never silent
+	CriticalSKUsMap map[string]bool
+	// Deliver in-stock alerts of critical SKUs during quiet hours
+	CriticalInQuietHours bool
+	// SKU (or SKU prefix wildcard, or "default") -> forum topic in the primary chat
+	TopicThreadIDs map[string]int
+	// SKU (or SKU prefix wildcard) -> quantity below which an in-stock product gets a low-stock alert
+	LowStockThresholds map[string]int
+	// Local time (HH:MM) of the daily briefing, disabled when empty
+	BriefingTime string
+	// Quiet hours in minutes after local midnight, from start (inclusive) to end (exclusive), wrapping past
+	// midnight when start is later than end. Disabled when both are equal.
+	QuietHoursStart int
+	QuietHoursEnd   int
+	// No notification is sent before this time, e.g. while away on vacation, disabled when zero
+	PauseUntil time.Time
+	// Weekday and local time (HH:MM) of the weekly digest, disabled when the time is empty
+	WeeklyDigestDay  time.Weekday
+	WeeklyDigestTime string
+	// Amul store code (usually the state name) whose stock is checked
+	Store string
+	// Google service-account key file, spreadsheet and range receiving every observation, disabled when empty
+	SheetsCredentialsFile string
+	SheetsSpreadsheetID   string
+	SheetsRange           string
+	// Notion integration token and database kept up to date with current stock, disabled when empty
+	NotionToken      string
+	NotionDatabaseID string
+	// Check cycles taking longer than this send an alert, disabled when 0
+	SlowCheckThreshold time.Duration
+	// Identical stock-change alerts for a SKU within this window are sent once, disabled when 0
+	DedupeWindow time.Duration
+	// JSON file of runtime feature flags, re-read before every check, disabled when empty
+	FeatureFlagsFile string
+	// Minimum quantity increase between two checks that sends a quantity-jump alert, disabled when 0
+	QuantityJump int
+	// Consecutive checks a monitored SKU must be missing from the API before it's reported as possibly discontinued, disabled when 0
+	DiscontinuedAfter int
+	// MQTT broker URL receiving stock events, disabled when empty, and the topic with {sku} replaced by the SKU
+	MQTTBrokerURL string
+	MQTTTopic     string
+	// URLs receiving stock events as JSON, signed with the secret when it isn't empty
+	WebhookURLs   []string
+	WebhookSecret string
+	// Pushover application token and user (or group) key receiving every notification, disabled when empty
+	PushoverAppToken string
+	PushoverUserKey  string
+	// Gotify server URL and application token receiving every notification, disabled when empty
+	GotifyURL      string
+	GotifyAppToken string
+	// WhatsApp Cloud API access token, sending phone number ID and recipient phone numbers, disabled when empty
+	WhatsAppAccessToken   string
+	WhatsAppPhoneNumberID string
+	WhatsAppRecipients    []string
+	// Twilio account SID, auth token and sender number, or MSG91 auth key and flow template ID, sending SMS to the
+	// recipients for in-stock alerts of SMSSKUsMap, at most SMSMonthlyLimit messages per recipient and month
+	TwilioAccountSID string
+	TwilioAuthToken  string
+	TwilioFromNumber string
+	MSG91AuthKey     string
+	MSG91TemplateID  string
+	SMSRecipients    []string
+	SMSSKUsMap       map[string]bool
+	SMSMonthlyLimit  int
+	// Redis URL and key holding the stock state across restarts, disabled when the URL is empty
+	RedisURL string
+	RedisKey string
 }
 
-func parseSKUsToBeMonitored(monitoredSKUsRaw string) map[string]bool {
-	monitoredSKUsMap := make(map[string]bool)
-	for sku := range strings.SplitSeq(monitoredSKUsRaw, ",") {
-		trimmedSku := strings.TrimSpace(sku)
-		if trimmedSku != "" {
-			monitoredSKUsMap[trimmedSku] = true
+// parseCommaSeparatedSet parses comma separated values into a set, ignoring blanks
+func parseCommaSeparatedSet(raw string) map[string]bool {
+	values := make(map[string]bool)
+	for value := range strings.SplitSeq(raw, ",") {
+		trimmedValue := strings.TrimSpace(value)
+		if trimmedValue != "" {
+			values[trimmedValue] = true
 		}
 	}
+	return values
+}
+
+func parseSKUsToBeMonitored(monitoredSKUsRaw string) map[string]bool {
+	monitoredSKUsMap := parseCommaSeparatedSet(monitoredSKUsRaw)
 
 	log.Printf("Monitoring %d SKU/s", len(monitoredSKUsMap))
 	i := 1
@@ -37,29 +156,400 @@ func parseSKUsToBeMonitored(monitoredSKUsRaw string) map[string]bool {
 	return monitoredSKUsMap
 }
 
-func loadEnvVariables() (string, string, string, error) {
+// parseKeyValuePairs parses "key=value" entries separated by the given separator, skipping malformed entries
+func parseKeyValuePairs(raw, separator string) map[string]string {
+	pairs := make(map[string]string)
+	for entry := range strings.SplitSeq(raw, separator) {
+		key, value, found := strings.Cut(entry, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !found || key == "" || value == "" {
+			if strings.TrimSpace(entry) != "" {
+				log.Printf("Warning: Ignoring malformed entry '%s', expected key=value", strings.TrimSpace(entry))
+			}
+			continue
+		}
+		pairs[key] = value
+	}
+	return pairs
+}
+
+// resolveSKUAliases replaces monitored entries that are aliases with the SKUs they stand for. Aliases are resolved
+// once, an alias standing for another alias is not followed.
+func resolveSKUAliases(monitoredSKUsMap map[string]bool, skuAliases map[string]string) {
+	// The map is rewritten after ranging over it, keys added while ranging may or may not be visited
+	aliases := []string{}
+	for entry := range monitoredSKUsMap {
+		if _, isAlias := skuAliases[entry]; isAlias {
+			aliases = append(aliases, entry)
+		}
+	}
+	slices.Sort(aliases)
+
+	for _, alias := range aliases {
+		delete(monitoredSKUsMap, alias)
+	}
+	for _, alias := range aliases {
+		monitoredSKUsMap[skuAliases[alias]] = true
+		log.Printf("Resolved alias %s to %s", alias, skuAliases[alias])
+	}
+}
+
+// resolveKeyAliases rekeys per-SKU settings written against an alias to the SKU the alias stands for
+func resolveKeyAliases(perSKUSettings map[string]string, skuAliases map[string]string) {
+	aliases := []string{}
+	for key := range perSKUSettings {
+		if _, isAlias := skuAliases[key]; isAlias {
+			aliases = append(aliases, key)
+		}
+	}
+	slices.Sort(aliases)
+
+	values := make(map[string]string, len(aliases))
+	for _, alias := range aliases {
+		values[alias] = perSKUSettings[alias]
+		delete(perSKUSettings, alias)
+	}
+	for _, alias := range aliases {
+		perSKUSettings[skuAliases[alias]] = values[alias]
+	}
+}
+
+// parseTopicThreads converts SKU=thread pairs into forum topic IDs, skipping non-numeric thread IDs
+func parseTopicThreads(topicThreads map[string]string) map[string]int {
+	topicThreadIDs := make(map[string]int)
+	for key, rawThreadID := range topicThreads {
+		threadID, err := strconv.Atoi(rawThreadID)
+		if err != nil || threadID <= 0 {
+			log.Printf("Warning: Ignoring invalid topic thread ID '%s' for %s", rawThreadID, key)
+			continue
+		}
+		topicThreadIDs[key] = threadID
+	}
+	return topicThreadIDs
+}
+
+// parseLowStockThresholds converts SKU=quantity pairs into thresholds, skipping non-positive quantities
+func parseLowStockThresholds(lowStockThresholds map[string]string) map[string]int {
+	thresholds := make(map[string]int)
+	for key, rawQuantity := range lowStockThresholds {
+		quantity, err := strconv.Atoi(rawQuantity)
+		if err != nil || quantity <= 0 {
+			log.Printf("Warning: Ignoring invalid low-stock threshold '%s' for %s", rawQuantity, key)
+			continue
+		}
+		thresholds[key] = quantity
+	}
+	return thresholds
+}
+
+// extractVariantSubscriptions moves wildcard entries out of the monitored SKUs and returns their SKU prefixes
+func extractVariantSubscriptions(monitoredSKUsMap map[string]bool) map[string]bool {
+	monitoredVariantsMap := make(map[string]bool)
+	for sku := range monitoredSKUsMap {
+		if prefix, isWildcard := strings.CutSuffix(sku, variantWildcardSuffix); isWildcard {
+			delete(monitoredSKUsMap, sku)
+			if prefix != "" {
+				monitoredVariantsMap[prefix] = true
+				log.Printf("Monitoring every pack size of %s", prefix)
+			}
+		}
+	}
+	return monitoredVariantsMap
+}
+
+type envVariables struct {
+	telegramBotToken     string
+	telegramChatID       string
+	telegramExtraChatIDs string
+	telegramChannelID    string
+	monitoredSKUs        string
+	storeEncryptionKey   string
+	notionToken          string
+	redisURL             string
+	mqttBrokerURL        string
+	webhookSecret        string
+	pushoverAppToken     string
+	pushoverUserKey      string
+	gotifyURL            string
+	gotifyAppToken       string
+	whatsAppAccessToken  string
+	whatsAppPhoneID      string
+	whatsAppRecipients   string
+	twilioAccountSID     string
+	twilioAuthToken      string
+	twilioFromNumber     string
+	msg91AuthKey         string
+	msg91TemplateID      string
+	smsRecipients        string
+	httpToken            string
+	quietHours           string
+	quietHoursStart      string
+	quietHoursEnd        string
+}
+
+func loadEnvVariables() (envVariables, error) {
 	log.Println("Attempting to load .env file...")
 	cwd, _ := os.Getwd()
 	log.Printf("Current working directory: %s", cwd)
 	if err := godotenv.Load(); err != nil {
-		return "", "", "", err
+		return envVariables{}, err
 	} else {
 		log.Println(".env file loaded successfully (if found).")
 	}
 
-	telegramBotToken := strings.TrimSpace(os.Getenv("TELEGRAM_BOT_TOKEN"))
-	telegramChatID := strings.TrimSpace(os.Getenv("TELEGRAM_CHAT_ID"))
-	monitoredSKUs := strings.TrimSpace(os.Getenv("MONITORED_SKUS"))
+	return envVariables{
+		telegramBotToken:     strings.TrimSpace(os.Getenv("TELEGRAM_BOT_TOKEN")),
+		telegramChatID:       strings.TrimSpace(os.Getenv("TELEGRAM_CHAT_ID")),
+		telegramExtraChatIDs: strings.TrimSpace(os.Getenv("TELEGRAM_EXTRA_CHAT_IDS")),
+		telegramChannelID:    strings.TrimSpace(os.Getenv("TELEGRAM_BROADCAST_CHANNEL_ID")),
+		monitoredSKUs:        strings.TrimSpace(os.Getenv("MONITORED_SKUS")),
+		storeEncryptionKey:   strings.TrimSpace(os.Getenv("STORE_ENCRYPTION_KEY")),
+		notionToken:          strings.TrimSpace(os.Getenv("NOTION_TOKEN")),
+		redisURL:             strings.TrimSpace(os.Getenv("REDIS_URL")),
+		mqttBrokerURL:        strings.TrimSpace(os.Getenv("MQTT_BROKER_URL")),
+		webhookSecret:        strings.TrimSpace(os.Getenv("WEBHOOK_SECRET")),
+		pushoverAppToken:     strings.TrimSpace(os.Getenv("PUSHOVER_APP_TOKEN")),
+		pushoverUserKey:      strings.TrimSpace(os.Getenv("PUSHOVER_USER_KEY")),
+		gotifyURL:            strings.TrimSpace(os.Getenv("GOTIFY_URL")),
+		gotifyAppToken:       strings.TrimSpace(os.Getenv("GOTIFY_APP_TOKEN")),
+		whatsAppAccessToken:  strings.TrimSpace(os.Getenv("WHATSAPP_ACCESS_TOKEN")),
+		whatsAppPhoneID:      strings.TrimSpace(os.Getenv("WHATSAPP_PHONE_NUMBER_ID")),
+		whatsAppRecipients:   strings.TrimSpace(os.Getenv("WHATSAPP_RECIPIENTS")),
+		twilioAccountSID:     strings.TrimSpace(os.Getenv("TWILIO_ACCOUNT_SID")),
+		twilioAuthToken:      strings.TrimSpace(os.Getenv("TWILIO_AUTH_TOKEN")),
+		twilioFromNumber:     strings.TrimSpace(os.Getenv("TWILIO_FROM_NUMBER")),
+		msg91AuthKey:         strings.TrimSpace(os.Getenv("MSG91_AUTH_KEY")),
+		msg91TemplateID:      strings.TrimSpace(os.Getenv("MSG91_TEMPLATE_ID")),
+		smsRecipients:        strings.TrimSpace(os.Getenv("SMS_RECIPIENTS")),
+		httpToken:            strings.TrimSpace(os.Getenv("HTTP_TOKEN")),
+		quietHours:           strings.TrimSpace(os.Getenv("QUIET_HOURS")),
+		quietHoursStart:      strings.TrimSpace(os.Getenv("QUIET_HOURS_START")),
+		quietHoursEnd:        strings.TrimSpace(os.Getenv("QUIET_HOURS_END")),
+	}, nil
+}
+
+// loadStoreEncryptionKey derives the storage key from the key file if given, otherwise from STORE_ENCRYPTION_KEY
+func loadStoreEncryptionKey(keyFilePath, envSecret string) ([]byte, error) {
+	secret := envSecret
+	if keyFilePath != "" {
+		keyFileContents, err := os.ReadFile(keyFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading encryption key file: %w", err)
+		}
+		secret = strings.TrimSpace(string(keyFileContents))
+		if secret == "" {
+			return nil, errors.New("encryption key file is empty")
+		}
+	}
+
+	if secret == "" {
+		return nil, nil
+	}
+	log.Println("Stored files will be encrypted at rest")
+	return storage.DeriveKey(secret), nil
+}
 
-	return telegramBotToken, telegramChatID, monitoredSKUs, nil
+// parseParseMode normalizes the parse-mode flag to the exact name Telegram expects
+func parseParseMode(parseModeRaw string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(parseModeRaw)) {
+	case "", "html":
+		return "HTML", nil
+	case "markdownv2":
+		return "MarkdownV2", nil
+	default:
+		return "", fmt.Errorf("unsupported parse-mode '%s', use HTML or MarkdownV2", parseModeRaw)
+	}
+}
+
+// resolveQuietHours picks the quiet hours range from the flag, then QUIET_HOURS (a range or off),
+// then QUIET_HOURS_START and QUIET_HOURS_END, then the default
+func resolveQuietHours(flagValue string, env envVariables) string {
+	switch {
+	case strings.TrimSpace(flagValue) != "":
+		return flagValue
+	case env.quietHours != "":
+		return env.quietHours
+	case env.quietHoursStart != "" || env.quietHoursEnd != "":
+		return env.quietHoursStart + "-" + env.quietHoursEnd
+	default:
+		return defaultQuietHours
+	}
+}
+
+// parseQuietHours parses an HH:MM-HH:MM range into minutes after midnight, or 'off' into an empty range
+func parseQuietHours(quietHoursRaw string) (int, int, error) {
+	quietHoursRaw = strings.TrimSpace(quietHoursRaw)
+	if strings.EqualFold(quietHoursRaw, "off") {
+		return 0, 0, nil
+	}
+
+	startRaw, endRaw, found := strings.Cut(quietHoursRaw, "-")
+	start, startErr := time.Parse("15:04", strings.TrimSpace(startRaw))
+	end, endErr := time.Parse("15:04", strings.TrimSpace(endRaw))
+	if !found || startErr != nil || endErr != nil {
+		return 0, 0, fmt.Errorf("invalid quiet-hours '%s', expected HH:MM-HH:MM or off", quietHoursRaw)
+	}
+	return start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute(), nil
+}
+
+// parseBriefingTime validates an HH:MM time and returns it zero padded, so it can be compared as a string
+func parseBriefingTime(briefingTimeRaw string) (string, error) {
+	briefingTimeRaw = strings.TrimSpace(briefingTimeRaw)
+	if briefingTimeRaw == "" {
+		return "", nil
+	}
+	briefingTime, err := time.Parse("15:04", briefingTimeRaw)
+	if err != nil {
+		return "", fmt.Errorf("invalid briefing-time '%s', expected HH:MM", briefingTimeRaw)
+	}
+	return briefingTime.Format("15:04"), nil
+}
+
+// parsePauseUntil parses a date, meaning midnight at its start in the given location, or an RFC 3339 timestamp
+func parsePauseUntil(pauseUntilRaw string, location *time.Location) (time.Time, error) {
+	pauseUntilRaw = strings.TrimSpace(pauseUntilRaw)
+	if pauseUntilRaw == "" {
+		return time.Time{}, nil
+	}
+	if location == nil {
+		location = time.Local
+	}
+	if pauseUntil, err := time.ParseInLocation(time.DateOnly, pauseUntilRaw, location); err == nil {
+		return pauseUntil, nil
+	}
+	pauseUntil, err := time.Parse(time.RFC3339, pauseUntilRaw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid pause-until '%s', expected YYYY-MM-DD or an RFC 3339 timestamp", pauseUntilRaw)
+	}
+	return pauseUntil, nil
+}
+
+// parseWeeklyDigest parses a weekday and an HH:MM time, e.g. "sun 18:00" or "Sunday 18:00"
+func parseWeeklyDigest(weeklyDigestRaw string) (time.Weekday, string, error) {
+	dayRaw, timeRaw, found := strings.Cut(strings.TrimSpace(weeklyDigestRaw), " ")
+	if dayRaw == "" {
+		return time.Sunday, "", nil
+	}
+
+	invalid := fmt.Errorf("invalid weekly-digest '%s', expected a weekday and HH:MM e.g. 'sun 18:00'", weeklyDigestRaw)
+	if !found {
+		return time.Sunday, "", invalid
+	}
+	digestTime, err := parseBriefingTime(timeRaw)
+	if err != nil || digestTime == "" {
+		return time.Sunday, "", invalid
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := strings.ToLower(day.String())
+		if strings.EqualFold(dayRaw, name) || strings.EqualFold(dayRaw, name[:3]) {
+			return day, digestTime, nil
+		}
+	}
+	return time.Sunday, "", invalid
+}
+
+// parseStore normalizes a store code, using the default store when it is empty
+func parseStore(storeRaw string) string {
+	store := strings.ToLower(strings.TrimSpace(storeRaw))
+	if store == "" {
+		return DefaultStore
+	}
+	return store
+}
+
+// parseExtraChatIDs parses comma separated chat IDs, dropping duplicates and the primary chat
+func parseExtraChatIDs(extraChatIDsRaw, primaryChatID string) []string {
+	extraChatIDs := []string{}
+	seen := map[string]bool{primaryChatID: true}
+	for chatID := range strings.SplitSeq(extraChatIDsRaw, ",") {
+		trimmedChatID := strings.TrimSpace(chatID)
+		if trimmedChatID != "" && !seen[trimmedChatID] {
+			seen[trimmedChatID] = true
+			extraChatIDs = append(extraChatIDs, trimmedChatID)
+		}
+	}
+	return extraChatIDs
+}
+
+// parseWebhookURLs parses comma separated http(s) URLs, dropping duplicates
+func parseWebhookURLs(webhookURLsRaw string) ([]string, error) {
+	webhookURLs := []string{}
+	seen := map[string]bool{}
+	for webhookURL := range strings.SplitSeq(webhookURLsRaw, ",") {
+		trimmedURL := strings.TrimSpace(webhookURL)
+		if trimmedURL == "" || seen[trimmedURL] {
+			continue
+		}
+		parsed, err := url.Parse(trimmedURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid webhook URL '%s', expected an http or https URL", trimmedURL)
+		}
+		seen[trimmedURL] = true
+		webhookURLs = append(webhookURLs, trimmedURL)
+	}
+	return webhookURLs, nil
+}
+
+// parsePhoneNumbers parses comma separated phone numbers in international format, dropping the leading +,
+// spaces and duplicates
+func parsePhoneNumbers(recipientsRaw string) ([]string, error) {
+	recipients := []string{}
+	seen := map[string]bool{}
+	for recipient := range strings.SplitSeq(recipientsRaw, ",") {
+		number := strings.TrimPrefix(strings.ReplaceAll(strings.TrimSpace(recipient), " ", ""), "+")
+		if number == "" || seen[number] {
+			continue
+		}
+		if _, err := strconv.ParseUint(number, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid phone number '%s', expected a number with country code", strings.TrimSpace(recipient))
+		}
+		seen[number] = true
+		recipients = append(recipients, number)
+	}
+	return recipients, nil
 }
 
 func ParseConfiguration() (*AppConfig, error) {
 	defaultCheckInterval, _ := time.ParseDuration("60m")
 	checkIntervalPtr := flag.Duration("check-interval", defaultCheckInterval, "interval at which the app will check for stock")
-	monitoredRawSKUs := flag.String("monitored-skus", "", "comma seprated values of SKUs to be monitored")
+	monitoredRawSKUs := flag.String("monitored-skus", "", "comma seprated values of SKUs to be monitored, use PREFIX_* to monitor every pack size of a product")
 	timezonePtr := flag.String("timezone", "", "timezone")
-	var telegramBotToken, telegramChatID string
+	skuAliasesPtr := flag.String("sku-aliases", "", "comma seprated alias=SKU pairs usable in place of SKUs, e.g. rose-lassi=LASCP40_30")
+	skuNotesPtr := flag.String("sku-notes", "", "semicolon separated SKU=note pairs shown in alerts, e.g. LASCP40_30=buy 2 boxes for dad")
+	alertPhotosPtr := flag.Bool("alert-photos", false, "send in-stock alerts on Telegram as a photo of the product with the alert as caption")
+	offerAlertsPtr := flag.Bool("offer-alerts", false, "send an alert when a monitored product goes on offer (price below MRP)")
+	criticalSKUsPtr := flag.String("critical-skus", "", "comma seprated SKUs or aliases whose in-stock alerts are pinned in the chat with an urgency note")
+	criticalInQuietHoursPtr := flag.Bool("critical-ignore-quiet-hours", false, "deliver in-stock alerts of critical-skus even during quiet hours")
+	topicThreadsPtr := flag.String("topic-threads", "", "comma seprated SKU=thread-id pairs routing alerts to forum topics in the primary chat, use default=thread-id for other messages")
+	lowStockThresholdsPtr := flag.String("low-stock-thresholds", "", "comma seprated SKU=quantity pairs, an in-stock product dropping below its quantity gets a low-stock alert")
+	quantityJumpPtr := flag.Int("quantity-jump", 0, "send an alert when a monitored product's quantity grows by at least this many units between checks (0 disables)")
+	parseModePtr := flag.String("parse-mode", "HTML", "telegram message formatting, HTML or MarkdownV2")
+	httpAddrPtr := flag.String("http-addr", "", "address for the HTTP server exposing Prometheus metrics on /metrics, e.g. :9090")
+	httpTokenPtr := flag.String("http-token", "", "bearer token required by every HTTP endpoint, overrides HTTP_TOKEN")
+	historyFilePtr := flag.String("history-file", "", "JSON Lines file recording stock and price history of monitored SKUs")
+	outboxFilePtr := flag.String("outbox-file", "", "JSON Lines file recording every outgoing notification, pending entries are retried on startup")
+	listDeadLettersPtr := flag.Bool("list-dead-letters", false, "print notifications that exhausted their retries from the outbox file and exit")
+	redriveDeadLettersPtr := flag.Bool("redrive-dead-letters", false, "retry every dead-lettered notification from the outbox file on startup")
+	encryptionKeyFilePtr := flag.String("encryption-key-file", "", "file holding the secret used to encrypt stored files, overrides STORE_ENCRYPTION_KEY")
+	silentAlertsPtr := flag.String("silent-alerts", "", "comma seprated notification types sent without sound (startup, initial-stock, in-stock, out-of-stock, assumed-out-of-stock, on-offer, briefing, slow-check, possibly-discontinued, low-stock, quantity-jump, weekly-digest, quiet-hours-summary) or 'all'")
+	storePtr := flag.String("store", DefaultStore, "Amul store code whose stock is checked, usually the lowercase state name e.g. maharashtra")
+	sheetsCredentialsFilePtr := flag.String("sheets-credentials-file", "", "Google service-account key file used to append every observation to a Google Sheet")
+	sheetsSpreadsheetIDPtr := flag.String("sheets-spreadsheet-id", "", "ID of the Google Sheet receiving observations, shared with the service account")
+	sheetsRangePtr := flag.String("sheets-range", "Sheet1!A:F", "sheet range whose table observations are appended to")
+	notionDatabaseIDPtr := flag.String("notion-database-id", "", "ID of a Notion database kept up to date with the stock of every monitored SKU, needs NOTION_TOKEN")
+	dedupeWindowPtr := flag.Duration("dedupe-window", 0, "send an identical stock-change alert for a SKU at most once within this window, e.g. 30m (0 disables)")
+	slowCheckThresholdPtr := flag.Duration("slow-check-threshold", 0, "send an alert when a check cycle takes longer than this, e.g. 30s (0 disables)")
+	featureFlagsFilePtr := flag.String("feature-flags-file", "", "JSON file of feature flags (offer-alerts, daily-briefing, restock-insights) re-read before every check")
+	discontinuedAfterPtr := flag.Int("discontinued-after", 0, "report a monitored SKU as possibly discontinued once after it is missing from this many checks in a row (0 disables)")
+	mqttTopicPtr := flag.String("mqtt-topic", "amul-notifier/{sku}", "MQTT topic receiving stock events when MQTT_BROKER_URL is set, {sku} is replaced by the SKU")
+	webhookURLsPtr := flag.String("webhook-urls", "", "comma seprated URLs receiving a JSON POST on every stock change, signed with WEBHOOK_SECRET when set")
+	redisKeyPtr := flag.String("redis-key", "amul-notifier:state", "Redis key holding the stock state when REDIS_URL is set")
+	smsSKUsPtr := flag.String("sms-skus", "", "comma seprated SKUs or aliases whose in-stock alerts are also sent by SMS to SMS_RECIPIENTS")
+	smsMonthlyLimitPtr := flag.Int("sms-monthly-limit", 20, "most SMS sent to each recipient in a calendar month, later alerts are skipped")
+	weeklyDigestPtr := flag.String("weekly-digest", "", "weekday and local time of a weekly digest of restocks, time in stock and price changes e.g. 'sun 18:00', needs history-file")
+	quietHoursPtr := flag.String("quiet-hours", "", "local time range (HH:MM-HH:MM, in timezone) during which notifications are suppressed, may wrap past midnight, or 'off', overrides QUIET_HOURS (default "+defaultQuietHours+")")
+	pauseUntilPtr := flag.String("pause-until", "", "send no notifications before this date (YYYY-MM-DD, in timezone) or RFC 3339 time while checks go on, e.g. during a vacation")
+	briefingTimePtr := flag.String("briefing-time", "", "local time (HH:MM) of a daily briefing summarizing stock, the last day's changes and price changes")
 	flag.Parse()
 
 	timeLocation, err := time.LoadLocation(*timezonePtr)
@@ -67,11 +557,95 @@ func ParseConfiguration() (*AppConfig, error) {
 		log.Println("Failed to parse timezone argument, disabling quiet hours")
 	}
 
-	telegramBotToken, telegramChatID, *monitoredRawSKUs, err = loadEnvVariables()
+	env, err := loadEnvVariables()
+	if err != nil {
+		return nil, err
+	}
+	telegramBotToken, telegramChatID := env.telegramBotToken, env.telegramChatID
+	*monitoredRawSKUs = env.monitoredSKUs
+
+	parseMode, err := parseParseMode(*parseModePtr)
+	if err != nil {
+		return nil, err
+	}
+
+	quietHoursStart, quietHoursEnd, err := parseQuietHours(resolveQuietHours(*quietHoursPtr, env))
+	if err != nil {
+		return nil, err
+	}
+
+	pauseUntil, err := parsePauseUntil(*pauseUntilPtr, timeLocation)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().Before(pauseUntil) {
+		log.Printf("Notifications are paused until %s", pauseUntil.Format(time.RFC3339))
+	}
+
+	briefingTime, err := parseBriefingTime(*briefingTimePtr)
+	if err != nil {
+		return nil, err
+	}
+
+	weeklyDigestDay, weeklyDigestTime, err := parseWeeklyDigest(*weeklyDigestPtr)
+	if err != nil {
+		return nil, err
+	}
+	if weeklyDigestTime != "" && strings.TrimSpace(*historyFilePtr) == "" {
+		return nil, errors.New("weekly-digest requires history-file to be set")
+	}
+
+	webhookURLs, err := parseWebhookURLs(*webhookURLsPtr)
 	if err != nil {
 		return nil, err
 	}
 
+	storeEncryptionKey, err := loadStoreEncryptionKey(strings.TrimSpace(*encryptionKeyFilePtr), env.storeEncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if (*listDeadLettersPtr || *redriveDeadLettersPtr) && strings.TrimSpace(*outboxFilePtr) == "" {
+		return nil, errors.New("list-dead-letters and redrive-dead-letters require outbox-file to be set")
+	}
+
+	if (strings.TrimSpace(*sheetsCredentialsFilePtr) == "") != (strings.TrimSpace(*sheetsSpreadsheetIDPtr) == "") {
+		return nil, errors.New("sheets-credentials-file and sheets-spreadsheet-id must be set together")
+	}
+
+	if strings.TrimSpace(*notionDatabaseIDPtr) != "" && env.notionToken == "" {
+		return nil, errors.New("notion-database-id requires NOTION_TOKEN to be set")
+	}
+
+	if (env.pushoverAppToken == "") != (env.pushoverUserKey == "") {
+		return nil, errors.New("PUSHOVER_APP_TOKEN and PUSHOVER_USER_KEY must be set together")
+	}
+
+	if (env.gotifyURL == "") != (env.gotifyAppToken == "") {
+		return nil, errors.New("GOTIFY_URL and GOTIFY_APP_TOKEN must be set together")
+	}
+
+	whatsAppRecipients, err := parsePhoneNumbers(env.whatsAppRecipients)
+	if err != nil {
+		return nil, err
+	}
+	if (env.whatsAppAccessToken == "") != (env.whatsAppPhoneID == "") || (env.whatsAppAccessToken == "") != (len(whatsAppRecipients) == 0) {
+		return nil, errors.New("WHATSAPP_ACCESS_TOKEN, WHATSAPP_PHONE_NUMBER_ID and WHATSAPP_RECIPIENTS must be set together")
+	}
+
+	smsRecipients, err := parsePhoneNumbers(env.smsRecipients)
+	if err != nil {
+		return nil, err
+	}
+	twilioConfigured := env.twilioAccountSID != "" && env.twilioAuthToken != "" && env.twilioFromNumber != ""
+	msg91Configured := env.msg91AuthKey != "" && env.msg91TemplateID != ""
+	if len(smsRecipients) > 0 && twilioConfigured == msg91Configured {
+		return nil, errors.New("SMS_RECIPIENTS requires either TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and TWILIO_FROM_NUMBER or MSG91_AUTH_KEY and MSG91_TEMPLATE_ID")
+	}
+	if strings.TrimSpace(*smsSKUsPtr) != "" && len(smsRecipients) == 0 {
+		return nil, errors.New("sms-skus requires SMS_RECIPIENTS to be set")
+	}
+
 	if *monitoredRawSKUs == "" {
 		return nil, errors.New("monitored-skus argument is not set or empty. Please provide a comma-separated list of SKUs")
 	}
@@ -84,12 +658,95 @@ func ParseConfiguration() (*AppConfig, error) {
 		log.Printf("Telegram Bot Token Hint: Starts with '%s', ends with '%s'", telegramBotToken[:5], telegramBotToken[len(telegramBotToken)-5:])
 	}
 	log.Printf("Telegram Chat ID: %s", telegramChatID)
+	telegramExtraChatIDs := parseExtraChatIDs(env.telegramExtraChatIDs, telegramChatID)
+	if len(telegramExtraChatIDs) > 0 {
+		log.Printf("Telegram Extra Chat IDs: %s", strings.Join(telegramExtraChatIDs, ", "))
+	}
+	if env.telegramChannelID != "" {
+		if env.telegramChannelID == telegramChatID || slices.Contains(telegramExtraChatIDs, env.telegramChannelID) {
+			return nil, errors.New("TELEGRAM_BROADCAST_CHANNEL_ID must differ from the chats that receive every notification")
+		}
+		log.Printf("Telegram Broadcast Channel ID: %s", env.telegramChannelID)
+	}
+
+	skuAliases := parseKeyValuePairs(*skuAliasesPtr, ",")
+	monitoredSKUsMap := parseSKUsToBeMonitored(*monitoredRawSKUs)
+	resolveSKUAliases(monitoredSKUsMap, skuAliases)
+	skuNotes := parseKeyValuePairs(*skuNotesPtr, ";")
+	resolveKeyAliases(skuNotes, skuAliases)
+	monitoredVariantsMap := extractVariantSubscriptions(monitoredSKUsMap)
+	criticalSKUsMap := parseCommaSeparatedSet(*criticalSKUsPtr)
+	resolveSKUAliases(criticalSKUsMap, skuAliases)
+	topicThreads := parseKeyValuePairs(*topicThreadsPtr, ",")
+	resolveKeyAliases(topicThreads, skuAliases)
+	smsSKUsMap := parseCommaSeparatedSet(*smsSKUsPtr)
+	resolveSKUAliases(smsSKUsMap, skuAliases)
+	lowStockThresholds := parseKeyValuePairs(*lowStockThresholdsPtr, ",")
+	resolveKeyAliases(lowStockThresholds, skuAliases)
 
 	return &AppConfig{
-		CheckInterval:    *checkIntervalPtr,
-		Timezone:         timeLocation,
-		TelegramBotToken: telegramBotToken,
-		TelegramChatId:   telegramChatID,
-		MonitoredSKUsMap: parseSKUsToBeMonitored(*monitoredRawSKUs),
+		CheckInterval:         *checkIntervalPtr,
+		Timezone:              timeLocation,
+		QuietHoursStart:       quietHoursStart,
+		QuietHoursEnd:         quietHoursEnd,
+		PauseUntil:            pauseUntil,
+		TelegramBotToken:      telegramBotToken,
+		TelegramChatId:        telegramChatID,
+		ParseMode:             parseMode,
+		TelegramExtraChatIds:  telegramExtraChatIDs,
+		TelegramChannelID:     env.telegramChannelID,
+		MonitoredSKUsMap:      monitoredSKUsMap,
+		MonitoredVariantsMap:  monitoredVariantsMap,
+		SKUAliases:            skuAliases,
+		SKUNotes:              skuNotes,
+		OfferAlerts:           *offerAlertsPtr,
+		AlertPhotos:           *alertPhotosPtr,
+		HTTPAddr:              strings.TrimSpace(*httpAddrPtr),
+		HTTPToken:             cmp.Or(strings.TrimSpace(*httpTokenPtr), env.httpToken),
+		HistoryFile:           strings.TrimSpace(*historyFilePtr),
+		OutboxFile:            strings.TrimSpace(*outboxFilePtr),
+		StoreEncryptionKey:    storeEncryptionKey,
+		ListDeadLetters:       *listDeadLettersPtr,
+		RedriveDeadLetters:    *redriveDeadLettersPtr,
+		SilentAlerts:          parseCommaSeparatedSet(*silentAlertsPtr),
+		CriticalSKUsMap:       criticalSKUsMap,
+		CriticalInQuietHours:  *criticalInQuietHoursPtr,
+		TopicThreadIDs:        parseTopicThreads(topicThreads),
+		LowStockThresholds:    parseLowStockThresholds(lowStockThresholds),
+		BriefingTime:          briefingTime,
+		WeeklyDigestDay:       weeklyDigestDay,
+		WeeklyDigestTime:      weeklyDigestTime,
+		Store:                 parseStore(*storePtr),
+		SheetsCredentialsFile: strings.TrimSpace(*sheetsCredentialsFilePtr),
+		SheetsSpreadsheetID:   strings.TrimSpace(*sheetsSpreadsheetIDPtr),
+		SheetsRange:           strings.TrimSpace(*sheetsRangePtr),
+		NotionToken:           env.notionToken,
+		NotionDatabaseID:      strings.TrimSpace(*notionDatabaseIDPtr),
+		SlowCheckThreshold:    *slowCheckThresholdPtr,
+		DedupeWindow:          max(*dedupeWindowPtr, 0),
+		FeatureFlagsFile:      strings.TrimSpace(*featureFlagsFilePtr),
+		QuantityJump:          max(*quantityJumpPtr, 0),
+		DiscontinuedAfter:     max(*discontinuedAfterPtr, 0),
+		MQTTBrokerURL:         env.mqttBrokerURL,
+		MQTTTopic:             strings.TrimSpace(*mqttTopicPtr),
+		WebhookURLs:           webhookURLs,
+		WebhookSecret:         env.webhookSecret,
+		PushoverAppToken:      env.pushoverAppToken,
+		PushoverUserKey:       env.pushoverUserKey,
+		GotifyURL:             env.gotifyURL,
+		GotifyAppToken:        env.gotifyAppToken,
+		WhatsAppAccessToken:   env.whatsAppAccessToken,
+		WhatsAppPhoneNumberID: env.whatsAppPhoneID,
+		WhatsAppRecipients:    whatsAppRecipients,
+		TwilioAccountSID:      env.twilioAccountSID,
+		TwilioAuthToken:       env.twilioAuthToken,
+		TwilioFromNumber:      env.twilioFromNumber,
+		MSG91AuthKey:          env.msg91AuthKey,
+		MSG91TemplateID:       env.msg91TemplateID,
+		SMSRecipients:         smsRecipients,
+		SMSSKUsMap:            smsSKUsMap,
+		SMSMonthlyLimit:       max(*smsMonthlyLimitPtr, 0),
+		RedisURL:              env.redisURL,
+		RedisKey:              strings.TrimSpace(*redisKeyPtr),
 	}, nil
 }
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -11,4 +14,193 @@ func TestPassedConfig(t *testing.T) {
 		monitoredSKU := parseSKUsToBeMonitored("SKU01,SKU02,SKU03")
 		assert.Equal(t,3, len(monitoredSKU))
 	})
+
+	t.Run("Check for variant subscriptions", func(t *testing.T) {
+		monitoredSKU := parseSKUsToBeMonitored("SKU01_02,HPPCP01_*,_*")
+		monitoredVariants := extractVariantSubscriptions(monitoredSKU)
+		assert.Equal(t, map[string]bool{"SKU01_02": true}, monitoredSKU)
+		assert.Equal(t, map[string]bool{"HPPCP01": true}, monitoredVariants)
+	})
+
+	t.Run("Check for SKU aliases", func(t *testing.T) {
+		skuAliases := parseKeyValuePairs("rose-lassi=LASCP40_30, paneer = HPPCP01_*,broken", ",")
+		assert.Equal(t, map[string]string{"rose-lassi": "LASCP40_30", "paneer": "HPPCP01_*"}, skuAliases)
+
+		monitoredSKU := parseSKUsToBeMonitored("rose-lassi,paneer,SKU01")
+		resolveSKUAliases(monitoredSKU, skuAliases)
+		assert.Equal(t, map[string]bool{"LASCP40_30": true, "HPPCP01_*": true, "SKU01": true}, monitoredSKU)
+	})
+
+	t.Run("Check for SKU notes", func(t *testing.T) {
+		skuNotes := parseKeyValuePairs("rose-lassi=buy 2 boxes, for dad;SKU01=gym", ";")
+		resolveKeyAliases(skuNotes, map[string]string{"rose-lassi": "LASCP40_30"})
+		assert.Equal(t, map[string]string{"LASCP40_30": "buy 2 boxes, for dad", "SKU01": "gym"}, skuNotes)
+	})
+
+	t.Run("Check for aliases of aliases", func(t *testing.T) {
+		skuAliases := map[string]string{"lassi": "rose-lassi", "rose-lassi": "LASCP40_30"}
+		for range 20 {
+			monitoredSKU := map[string]bool{"lassi": true}
+			resolveSKUAliases(monitoredSKU, skuAliases)
+			assert.Equal(t, map[string]bool{"rose-lassi": true}, monitoredSKU)
+
+			skuNotes := map[string]string{"lassi": "for mom", "rose-lassi": "for dad"}
+			resolveKeyAliases(skuNotes, skuAliases)
+			assert.Equal(t, map[string]string{"rose-lassi": "for mom", "LASCP40_30": "for dad"}, skuNotes)
+		}
+	})
+
+	t.Run("Check for extra chat IDs", func(t *testing.T) {
+		extraChatIDs := parseExtraChatIDs(" -1001, 42,-1001,,100", "100")
+		assert.Equal(t, []string{"-1001", "42"}, extraChatIDs)
+	})
+
+	t.Run("Check for topic threads", func(t *testing.T) {
+		topicThreads := parseKeyValuePairs("whey=12,HPPCP01_*=7,default=3,LASCP61_30=abc", ",")
+		resolveKeyAliases(topicThreads, map[string]string{"whey": "WPCCP01_01"})
+		assert.Equal(t, map[string]int{"WPCCP01_01": 12, "HPPCP01_*": 7, "default": 3}, parseTopicThreads(topicThreads))
+	})
+
+	t.Run("Check for low-stock thresholds", func(t *testing.T) {
+		lowStockThresholds := parseKeyValuePairs("whey=20,HPPCP01_*=5,LASCP61_30=0,LASCP40_30=few", ",")
+		resolveKeyAliases(lowStockThresholds, map[string]string{"whey": "WPCCP01_01"})
+		assert.Equal(t, map[string]int{"WPCCP01_01": 20, "HPPCP01_*": 5}, parseLowStockThresholds(lowStockThresholds))
+	})
+
+	t.Run("Check for parse mode", func(t *testing.T) {
+		parseMode, err := parseParseMode("markdownv2")
+		assert.NoError(t, err)
+		assert.Equal(t, "MarkdownV2", parseMode)
+
+		parseMode, err = parseParseMode("")
+		assert.NoError(t, err)
+		assert.Equal(t, "HTML", parseMode)
+
+		_, err = parseParseMode("Markdown")
+		assert.Error(t, err)
+	})
+
+	t.Run("Check for quiet hours", func(t *testing.T) {
+		start, end, err := parseQuietHours("22:30-06:00")
+		assert.NoError(t, err)
+		assert.Equal(t, 22*60+30, start)
+		assert.Equal(t, 6*60, end)
+
+		start, end, err = parseQuietHours(" OFF ")
+		assert.NoError(t, err)
+		assert.Equal(t, start, end)
+
+		_, _, err = parseQuietHours("22:00")
+		assert.Error(t, err)
+	})
+
+	t.Run("Check for quiet hours from the environment", func(t *testing.T) {
+		assert.Equal(t, defaultQuietHours, resolveQuietHours("", envVariables{}))
+		assert.Equal(t, "off", resolveQuietHours("", envVariables{quietHours: "off", quietHoursStart: "22:00", quietHoursEnd: "06:00"}))
+		assert.Equal(t, "23:00-06:30", resolveQuietHours("23:00-06:30", envVariables{quietHours: "off"}))
+
+		start, end, err := parseQuietHours(resolveQuietHours("", envVariables{quietHoursStart: "22:00", quietHoursEnd: "06:00"}))
+		assert.NoError(t, err)
+		assert.Equal(t, 22*60, start)
+		assert.Equal(t, 6*60, end)
+
+		_, _, err = parseQuietHours(resolveQuietHours("", envVariables{quietHoursStart: "22:00"}))
+		assert.Error(t, err)
+	})
+
+	t.Run("Check for pause until", func(t *testing.T) {
+		kolkata, err := time.LoadLocation("Asia/Kolkata")
+		assert.NoError(t, err)
+
+		pauseUntil, err := parsePauseUntil("2025-06-01", kolkata)
+		assert.NoError(t, err)
+		assert.True(t, time.Date(2025, 6, 1, 0, 0, 0, 0, kolkata).Equal(pauseUntil))
+
+		pauseUntil, err = parsePauseUntil("2025-06-01T18:30:00+05:30", nil)
+		assert.NoError(t, err)
+		assert.True(t, time.Date(2025, 6, 1, 13, 0, 0, 0, time.UTC).Equal(pauseUntil))
+
+		pauseUntil, err = parsePauseUntil("", kolkata)
+		assert.NoError(t, err)
+		assert.True(t, pauseUntil.IsZero())
+
+		_, err = parsePauseUntil("next monday", kolkata)
+		assert.Error(t, err)
+	})
+
+	t.Run("Check for briefing time", func(t *testing.T) {
+		briefingTime, err := parseBriefingTime(" 8:30 ")
+		assert.NoError(t, err)
+		assert.Equal(t, "08:30", briefingTime)
+
+		briefingTime, err = parseBriefingTime("")
+		assert.NoError(t, err)
+		assert.Equal(t, "", briefingTime)
+
+		_, err = parseBriefingTime("8am")
+		assert.Error(t, err)
+	})
+
+	t.Run("Check for weekly digest", func(t *testing.T) {
+		day, digestTime, err := parseWeeklyDigest("Sun 18:00")
+		assert.NoError(t, err)
+		assert.Equal(t, time.Sunday, day)
+		assert.Equal(t, "18:00", digestTime)
+
+		day, digestTime, err = parseWeeklyDigest("friday 7:30")
+		assert.NoError(t, err)
+		assert.Equal(t, time.Friday, day)
+		assert.Equal(t, "07:30", digestTime)
+
+		_, digestTime, err = parseWeeklyDigest("")
+		assert.NoError(t, err)
+		assert.Equal(t, "", digestTime)
+
+		for _, invalid := range []string{"sunday", "18:00", "someday 18:00", "sun 6pm"} {
+			_, _, err = parseWeeklyDigest(invalid)
+			assert.Error(t, err)
+		}
+	})
+
+	t.Run("Check for webhook URLs", func(t *testing.T) {
+		webhookURLs, err := parseWebhookURLs(" https://example.com/hook, http://192.168.1.5:1880/amul,,https://example.com/hook")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"https://example.com/hook", "http://192.168.1.5:1880/amul"}, webhookURLs)
+
+		_, err = parseWebhookURLs("example.com/hook")
+		assert.Error(t, err)
+	})
+
+	t.Run("Check for phone numbers", func(t *testing.T) {
+		recipients, err := parsePhoneNumbers(" +91 98765 43210,,919876543210, 14155550123")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"919876543210", "14155550123"}, recipients)
+
+		_, err = parsePhoneNumbers("98765-43210")
+		assert.Error(t, err)
+	})
+
+	t.Run("Check for store code", func(t *testing.T) {
+		assert.Equal(t, "maharashtra", parseStore(" Maharashtra "))
+		assert.Equal(t, DefaultStore, parseStore(""))
+	})
+
+	t.Run("Check for store encryption key", func(t *testing.T) {
+		key, err := loadStoreEncryptionKey("", "")
+		assert.NoError(t, err)
+		assert.Nil(t, key)
+
+		key, err = loadStoreEncryptionKey("", "env-secret")
+		assert.NoError(t, err)
+		assert.Equal(t, 32, len(key))
+
+		keyFile := filepath.Join(t.TempDir(), "key")
+		assert.NoError(t, os.WriteFile(keyFile, []byte("file-secret\n"), 0o600))
+		fileKey, err := loadStoreEncryptionKey(keyFile, "env-secret")
+		assert.NoError(t, err)
+		assert.NotEqual(t, key, fileKey)
+
+		_, err = loadStoreEncryptionKey(filepath.Join(t.TempDir(), "missing"), "")
+		assert.Error(t, err)
+	})
 }
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"amul-notifier/internal/history"
+	"errors"
+	"flag"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Settings for the export-history subcommand
+type ExportConfig struct {
+	HistoryFile        string
+	SKUs               map[string]bool
+	From               time.Time
+	To                 time.Time
+	OutputFile         string
+	StoreEncryptionKey []byte
+}
+
+func ParseExportConfiguration(args []string) (*ExportConfig, error) {
+	flagSet := flag.NewFlagSet("export-history", flag.ContinueOnError)
+	historyFilePtr := flagSet.String("history-file", "", "JSON Lines history file written by --history-file")
+	skusPtr := flagSet.String("skus", "", "comma seprated SKUs to export, all SKUs when empty")
+	fromPtr := flagSet.String("from", "", "start of the export, YYYY-MM-DD or RFC 3339 (default: 30 days ago)")
+	toPtr := flagSet.String("to", "", "end of the export, YYYY-MM-DD or RFC 3339 (default: now)")
+	outputPtr := flagSet.String("output", "", "CSV file to write, stdout when empty")
+	encryptionKeyFilePtr := flagSet.String("encryption-key-file", "", "file holding the secret used to encrypt stored files, overrides STORE_ENCRYPTION_KEY")
+	if err := flagSet.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(*historyFilePtr) == "" {
+		return nil, errors.New("history-file argument is required for export-history")
+	}
+
+	// The .env file is optional here, it is only needed for STORE_ENCRYPTION_KEY
+	_ = godotenv.Load()
+	storeEncryptionKey, err := loadStoreEncryptionKey(strings.TrimSpace(*encryptionKeyFilePtr), strings.TrimSpace(os.Getenv("STORE_ENCRYPTION_KEY")))
+	if err != nil {
+		return nil, err
+	}
+
+	to := time.Now()
+	if *toPtr != "" {
+		if to, err = history.ParseTimeBound(*toPtr, true, time.Local); err != nil {
+			return nil, err
+		}
+	}
+	from := to.AddDate(0, 0, -30)
+	if *fromPtr != "" {
+		if from, err = history.ParseTimeBound(*fromPtr, false, time.Local); err != nil {
+			return nil, err
+		}
+	}
+	if from.After(to) {
+		return nil, errors.New("from must not be after to")
+	}
+
+	return &ExportConfig{
+		HistoryFile:        strings.TrimSpace(*historyFilePtr),
+		SKUs:               parseCommaSeparatedSet(*skusPtr),
+		From:               from,
+		To:                 to,
+		OutputFile:         strings.TrimSpace(*outputPtr),
+		StoreEncryptionKey: storeEncryptionKey,
+	}, nil
+}
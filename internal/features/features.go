@@ -0,0 +1,105 @@
+// Package features reads runtime feature flags from a JSON file, so optional behaviors can be toggled without a restart
+package features
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Flags that can be set in the file
+const (
+	OfferAlerts     = "offer-alerts"
+	DailyBriefing   = "daily-briefing"
+	RestockInsights = "restock-insights"
+)
+
+var knownFlags = []string{OfferAlerts, DailyBriefing, RestockInsights}
+
+// Flags holds the flags of a JSON file such as {"offer-alerts": true}. Flags missing from the file, and every
+// flag of a nil *Flags, fall back to the command-line configuration.
+type Flags struct {
+	path string
+
+	mu      sync.RWMutex
+	modTime time.Time
+	values  map[string]bool
+}
+
+// Open reads the feature flag file
+func Open(path string) (*Flags, error) {
+	f := &Flags{path: path, values: make(map[string]bool)}
+	if err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload re-reads the file when it changed since the last read, logging flags that changed.
+// On error the previous flags stay in effect.
+func (f *Flags) Reload() error {
+	if f == nil {
+		return nil
+	}
+
+	info, err := os.Stat(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		info, err = nil, nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading feature flag file: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var modTime time.Time
+	if info != nil {
+		modTime = info.ModTime()
+	}
+	if modTime.Equal(f.modTime) && !f.modTime.IsZero() {
+		return nil
+	}
+
+	values := make(map[string]bool)
+	if info != nil {
+		content, err := os.ReadFile(f.path)
+		if err != nil {
+			return fmt.Errorf("error reading feature flag file: %w", err)
+		}
+		if err := json.Unmarshal(content, &values); err != nil {
+			return fmt.Errorf("error parsing feature flag file: %w", err)
+		}
+	}
+	for name := range values {
+		if !slices.Contains(knownFlags, name) {
+			log.Printf("Warning: Ignoring unknown feature flag '%s', known flags are %v", name, knownFlags)
+			delete(values, name)
+		}
+	}
+
+	if !maps.Equal(values, f.values) {
+		log.Printf("Feature flags loaded from %s: %v", f.path, values)
+	}
+	f.values = values
+	f.modTime = modTime
+	return nil
+}
+
+// Enabled returns the value of a flag, or the fallback when the file doesn't set it
+func (f *Flags) Enabled(name string, fallback bool) bool {
+	if f == nil {
+		return fallback
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if value, set := f.values[name]; set {
+		return value
+	}
+	return fallback
+}
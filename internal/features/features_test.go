@@ -0,0 +1,43 @@
+package features
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	t.Run("Fall back to configuration for unset flags", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "features.json")
+		assert.NoError(t, os.WriteFile(path, []byte(`{"offer-alerts": false, "made-up": true}`), 0o600))
+
+		flags, err := Open(path)
+		assert.NoError(t, err)
+		assert.False(t, flags.Enabled(OfferAlerts, true))
+		assert.True(t, flags.Enabled(DailyBriefing, true))
+		assert.False(t, flags.Enabled("made-up", false))
+
+		var nilFlags *Flags
+		assert.True(t, nilFlags.Enabled(OfferAlerts, true))
+		assert.NoError(t, nilFlags.Reload())
+	})
+
+	t.Run("Reload changed files and keep flags on errors", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "features.json")
+		flags, err := Open(path)
+		assert.NoError(t, err)
+		assert.True(t, flags.Enabled(RestockInsights, true))
+
+		assert.NoError(t, os.WriteFile(path, []byte(`{"restock-insights": false}`), 0o600))
+		assert.NoError(t, flags.Reload())
+		assert.False(t, flags.Enabled(RestockInsights, true))
+
+		assert.NoError(t, os.WriteFile(path, []byte(`{not json`), 0o600))
+		assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+		assert.Error(t, flags.Reload())
+		assert.False(t, flags.Enabled(RestockInsights, true))
+	})
+}
@@ -0,0 +1,77 @@
+// Package gotify sends notifications to a self-hosted Gotify server
+package gotify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Message priorities. Gotify clients treat 8 and above as urgent and show 1 to 3 without a sound.
+const (
+	PriorityQuiet  = 2
+	PriorityNormal = 5
+	PriorityHigh   = 8
+)
+
+// Client sends to one Gotify application. A nil *Client sends nothing.
+type Client struct {
+	messageURL string
+	appToken   string
+	httpClient *http.Client
+}
+
+// New takes the server URL, e.g. https://gotify.example.com, and an application token
+func New(serverURL, appToken string) *Client {
+	return &Client{
+		messageURL: strings.TrimRight(serverURL, "/") + "/message",
+		appToken:   appToken,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Send delivers a Markdown message
+func (c *Client) Send(title, message string, priority int) error {
+	if c == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(map[string]any{
+		"title":    title,
+		"message":  message,
+		"priority": priority,
+		"extras": map[string]any{
+			"client::display": map[string]string{"contentType": "text/markdown"},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.messageURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", c.appToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		var response struct {
+			ErrorDescription string `json:"errorDescription"`
+		}
+		json.Unmarshal(body, &response)
+		return fmt.Errorf("gotify returned status %d: %s", resp.StatusCode, response.ErrorDescription)
+	}
+	return nil
+}
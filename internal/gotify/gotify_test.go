@@ -0,0 +1,50 @@
+package gotify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSend(t *testing.T) {
+	t.Run("Send a Markdown message with its priority", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/message", r.URL.Path)
+			assert.Equal(t, "app-token", r.Header.Get("X-Gotify-Key"))
+
+			var payload struct {
+				Title    string `json:"title"`
+				Message  string `json:"message"`
+				Priority int    `json:"priority"`
+				Extras   map[string]map[string]string
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			assert.Equal(t, "Amul Stock Notifier", payload.Title)
+			assert.Equal(t, "**Stock Available\\!**", payload.Message)
+			assert.Equal(t, PriorityHigh, payload.Priority)
+			assert.Equal(t, "text/markdown", payload.Extras["client::display"]["contentType"])
+			w.Write([]byte(`{"id":1}`))
+		}))
+		defer server.Close()
+
+		client := New(server.URL+"/", "app-token")
+		assert.NoError(t, client.Send("Amul Stock Notifier", "**Stock Available\\!**", PriorityHigh))
+	})
+
+	t.Run("Report API errors", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"Unauthorized","errorCode":401,"errorDescription":"you need to provide a valid access token or user credentials to access this api"}`))
+		}))
+		defer server.Close()
+
+		err := New(server.URL, "bad-token").Send("title", "message", PriorityNormal)
+		assert.ErrorContains(t, err, "valid access token")
+
+		var nilClient *Client
+		assert.NoError(t, nilClient.Send("title", "message", PriorityNormal))
+	})
+}
@@ -0,0 +1,369 @@
+package history
+
+import (
+	"amul-notifier/internal/storage"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Records older than this are dropped when the store is opened
+const retention = 365 * 24 * time.Hour
+
+// One observation of a SKU during a stock check
+type Record struct {
+	At           time.Time `json:"at"`
+	SKU          string    `json:"sku"`
+	Available    bool      `json:"available"`
+	Quantity     int       `json:"inventory_quantity"`
+	Price        int       `json:"price,omitempty"`
+	ComparePrice int       `json:"compare_price,omitempty"`
+	Store        string    `json:"store,omitempty"` // empty for records written before stores were recorded
+}
+
+// Store keeps stock and price history in an append-only JSON Lines file. A nil *Store records nothing.
+type Store struct {
+	mu   sync.RWMutex
+	path string
+	// AES-256 key used to encrypt each line, nil to store plaintext JSON
+	key     []byte
+	records []Record
+}
+
+// Open loads the history file, rewriting it without records past the retention period
+func Open(path string, key []byte) (*Store, error) {
+	return load(path, key, true)
+}
+
+// OpenReadOnly loads the history file without ever rewriting it, for tools running next to the notifier
+func OpenReadOnly(path string, key []byte) (*Store, error) {
+	return load(path, key, false)
+}
+
+func load(path string, key []byte, readWrite bool) (*Store, error) {
+	s := &Store{path: path, key: key}
+
+	data, err := storage.ReadFile(path, nil)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("History file %s not found, starting with an empty history", path)
+		return s, nil
+	}
+	pruned := 0
+	if err == nil {
+		s.records, pruned, err = parseRecords(data, key)
+	}
+	recovered := false
+	if errors.Is(err, storage.ErrCorrupt) {
+		s.records, pruned, err = recoverFromBackup(path, key, readWrite, err)
+		recovered = true
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading history file: %w", err)
+	}
+
+	slices.SortStableFunc(s.records, func(a, b Record) int { return a.At.Compare(b.At) })
+	log.Printf("Loaded %d history records from %s", len(s.records), path)
+
+	if pruned > 0 {
+		log.Printf("Pruning %d history records older than %v", pruned, retention)
+	}
+	// Records are only ever appended, so a recovered history has to be written back before the next append
+	if readWrite && (pruned > 0 || recovered) {
+		if err := s.rewrite(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// parseRecords decodes the lines of a history file, returning the records within the retention period
+// and how many older ones were dropped
+func parseRecords(data []byte, key []byte) ([]Record, int, error) {
+	records := []Record{}
+	cutoff := time.Now().Add(-retention)
+	pruned := 0
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		data, err := storage.OpenLine(line, key)
+		// A crash during an append can cut off the last line, which is skipped like a malformed one instead of
+		// treating the whole file as corrupt
+		if err != nil && i == len(lines)-1 {
+			log.Printf("Warning: Skipping incomplete history line %d: %v", i+1, err)
+			continue
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("error reading history line %d: %w", i+1, err)
+		}
+		var record Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			log.Printf("Warning: Skipping malformed history line %d: %v", i+1, err)
+			continue
+		}
+		if record.At.Before(cutoff) {
+			pruned++
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, pruned, nil
+}
+
+// recoverFromBackup loads the history from its last rewrite instead of a corrupt file. Read-only stores leave
+// the corrupt file in place for the notifier to move aside.
+func recoverFromBackup(path string, key []byte, readWrite bool, corruptErr error) ([]Record, int, error) {
+	if readWrite {
+		quarantinePath, err := storage.Quarantine(path)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w, and moving it aside failed: %w", corruptErr, err)
+		}
+		log.Printf("Warning: History file %s is corrupt (%v), moved it to %s and loading the backup", path, corruptErr, quarantinePath)
+	}
+
+	data, err := storage.ReadBackup(path, nil)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("No history backup found, starting with an empty history")
+		return []Record{}, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("history backup is unreadable too: %w", err)
+	}
+	records, pruned, err := parseRecords(data, key)
+	if err != nil {
+		return nil, 0, fmt.Errorf("history backup is unreadable too: %w", err)
+	}
+	return records, pruned, nil
+}
+
+// Append stores the records of one check, both in memory and in the history file
+func (s *Store) Append(records []Record) error {
+	if s == nil || len(records) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lines, err := s.encodeLines(records)
+	if err != nil {
+		return err
+	}
+
+	if err := storage.AppendFile(s.path, []byte(lines)); err != nil {
+		return fmt.Errorf("error writing history file: %w", err)
+	}
+
+	s.records = append(s.records, records...)
+	return nil
+}
+
+// Query returns the records of a SKU within [from, to], oldest first
+func (s *Store) Query(sku string, from, to time.Time) []Record {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matching := []Record{}
+	for _, record := range s.records {
+		if record.SKU == sku && !record.At.Before(from) && !record.At.After(to) {
+			matching = append(matching, record)
+		}
+	}
+	return matching
+}
+
+// SKUs returns every SKU with recorded history, sorted
+func (s *Store) SKUs() []string {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	skus := []string{}
+	for _, record := range s.records {
+		if !seen[record.SKU] {
+			seen[record.SKU] = true
+			skus = append(skus, record.SKU)
+		}
+	}
+	slices.Sort(skus)
+	return skus
+}
+
+// rewrite replaces the history file with the records held in memory, keeping the previous file as the backup.
+// Callers must hold the lock or own the store. Lines are sealed one by one, so the file itself is written unencrypted.
+func (s *Store) rewrite() error {
+	lines, err := s.encodeLines(s.records)
+	if err != nil {
+		return err
+	}
+	if err := storage.WriteFile(s.path, []byte(lines), nil); err != nil {
+		return fmt.Errorf("error rewriting history file: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) encodeLines(records []Record) (string, error) {
+	var lines strings.Builder
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return "", fmt.Errorf("error marshalling history record: %w", err)
+		}
+		line, err := storage.SealLine(data, s.key)
+		if err != nil {
+			return "", err
+		}
+		lines.WriteString(line)
+		lines.WriteString("\n")
+	}
+	return lines.String(), nil
+}
+
+// A stretch of consecutive checks that found a SKU in stock
+type InStockRun struct {
+	Start        time.Time
+	End          time.Time
+	PeakQuantity int
+}
+
+// LastInStockRun returns the most recent in-stock run of a SKU among records before the given time,
+// which is the run that just ended when a SKU sells out
+func (s *Store) LastInStockRun(sku string, before time.Time) (InStockRun, bool) {
+	if s == nil {
+		return InStockRun{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var run InStockRun
+	found := false
+	for i := len(s.records) - 1; i >= 0; i-- {
+		record := s.records[i]
+		if record.SKU != sku || !record.At.Before(before) {
+			continue
+		}
+		if !record.Available {
+			if found {
+				break
+			}
+			continue
+		}
+		if !found {
+			run.End = record.At
+			found = true
+		}
+		run.Start = record.At
+		run.PeakQuantity = max(run.PeakQuantity, record.Quantity)
+	}
+	return run, found
+}
+
+// RestockGaps returns how long each completed out-of-stock spell of a SKU lasted in a store, oldest first.
+// Only spells that started after the SKU was seen in stock and ended with a restock are counted.
+// Records without a store predate stores being recorded and count for every store.
+func (s *Store) RestockGaps(sku, store string) []time.Duration {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var gaps []time.Duration
+	var outSince time.Time
+	seenInStock := false
+	for _, record := range s.records {
+		if record.SKU != sku || (record.Store != "" && record.Store != store) {
+			continue
+		}
+		switch {
+		case record.Available && !outSince.IsZero():
+			gaps = append(gaps, record.At.Sub(outSince))
+			outSince = time.Time{}
+		case !record.Available && seenInStock && outSince.IsZero():
+			outSince = record.At
+		}
+		seenInStock = seenInStock || record.Available
+	}
+	return gaps
+}
+
+// Export returns the records of the given SKUs (every SKU when empty) within [from, to], oldest first
+func (s *Store) Export(skus map[string]bool, from, to time.Time) []Record {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matching := []Record{}
+	for _, record := range s.records {
+		if (len(skus) == 0 || skus[record.SKU]) && !record.At.Before(from) && !record.At.After(to) {
+			matching = append(matching, record)
+		}
+	}
+	return matching
+}
+
+// Column names of the tabular form of records, matching the values returned by Record.Fields
+var FieldNames = []string{"timestamp", "sku", "available", "quantity", "price", "mrp"}
+
+// Fields returns the record as text columns for CSV files and spreadsheets
+func (r Record) Fields() []string {
+	return []string{
+		r.At.Format(time.RFC3339),
+		r.SKU,
+		strconv.FormatBool(r.Available),
+		strconv.Itoa(r.Quantity),
+		strconv.Itoa(r.Price),
+		strconv.Itoa(max(r.ComparePrice, r.Price)),
+	}
+}
+
+// WriteCSV writes records as CSV with a header row, for spreadsheets and offline analysis
+func WriteCSV(w io.Writer, records []Record) error {
+	csvWriter := csv.NewWriter(w)
+	if err := csvWriter.Write(FieldNames); err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := csvWriter.Write(record.Fields()); err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// ParseTimeBound parses a date (YYYY-MM-DD) or RFC 3339 timestamp. Dates used as an upper bound cover the whole day.
+func ParseTimeBound(value string, endOfDay bool, loc *time.Location) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	parsed, err := time.ParseInLocation(time.DateOnly, value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time '%s', use YYYY-MM-DD or RFC 3339", value)
+	}
+	if endOfDay {
+		parsed = parsed.Add(24*time.Hour - time.Nanosecond)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,195 @@
+package history
+
+import (
+	"amul-notifier/internal/storage"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryStore(t *testing.T) {
+	t.Run("Append, reopen and query", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.jsonl")
+		key := storage.DeriveKey("history key")
+		now := time.Now().UTC().Truncate(time.Second)
+
+		store, err := Open(path, key)
+		assert.NoError(t, err)
+		assert.NoError(t, store.Append([]Record{
+			{At: now.Add(-2 * time.Hour), SKU: "SKU02", Available: false},
+			{At: now.Add(-2 * time.Hour), SKU: "SKU01", Available: false, Price: 450},
+		}))
+		assert.NoError(t, store.Append([]Record{{At: now.Add(-time.Hour), SKU: "SKU01", Available: true, Price: 400, ComparePrice: 450}}))
+
+		reopened, err := Open(path, key)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"SKU01", "SKU02"}, reopened.SKUs())
+
+		records := reopened.Query("SKU01", now.Add(-3*time.Hour), now)
+		assert.Equal(t, 2, len(records))
+		assert.True(t, records[1].Available)
+		assert.Equal(t, 450, records[1].ComparePrice)
+		assert.Equal(t, 1, len(reopened.Query("SKU01", now.Add(-90*time.Minute), now)))
+	})
+
+	t.Run("Old records are pruned", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.jsonl")
+		store, err := Open(path, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, store.Append([]Record{
+			{At: time.Now().Add(-retention - time.Hour), SKU: "OLD"},
+			{At: time.Now(), SKU: "NEW"},
+		}))
+
+		reopened, err := Open(path, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"NEW"}, reopened.SKUs())
+	})
+
+	t.Run("Pruning keeps the previous file as a backup", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.jsonl")
+		store, err := Open(path, nil)
+		assert.NoError(t, err)
+		assert.NoError(t, store.Append([]Record{{At: time.Now().Add(-retention - time.Hour), SKU: "OLD"}}))
+
+		_, err = Open(path, nil)
+		assert.NoError(t, err)
+		backup, err := storage.ReadBackup(path, nil)
+		assert.NoError(t, err)
+		assert.Contains(t, string(backup), "OLD")
+	})
+
+	t.Run("Recover a corrupt file from the backup", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.jsonl")
+		key := storage.DeriveKey("history key")
+		store, err := Open(path, key)
+		assert.NoError(t, err)
+		assert.NoError(t, store.Append([]Record{
+			{At: time.Now().Add(-retention - time.Hour), SKU: "OLD"},
+			{At: time.Now(), SKU: "SKU01"},
+		}))
+		// Pruning on open rewrites the file, keeping it as the backup
+		_, err = Open(path, key)
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(path, []byte("enc:!!!\nenc:!!!\n"), 0o600))
+
+		recovered, err := Open(path, key)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"SKU01"}, recovered.SKUs())
+		quarantined, err := filepath.Glob(path + ".corrupt-*")
+		assert.NoError(t, err)
+		assert.Len(t, quarantined, 1)
+
+		reopened, err := Open(path, key)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"SKU01"}, reopened.SKUs())
+	})
+
+	t.Run("Skip a last line cut off by a crash", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.jsonl")
+		key := storage.DeriveKey("history key")
+		store, err := Open(path, key)
+		assert.NoError(t, err)
+		assert.NoError(t, store.Append([]Record{{At: time.Now(), SKU: "SKU01"}}))
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+		assert.NoError(t, err)
+		_, err = file.WriteString("enc:AAAA")
+		assert.NoError(t, err)
+		file.Close()
+
+		reopened, err := Open(path, key)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"SKU01"}, reopened.SKUs())
+	})
+
+	t.Run("Export selected SKUs as CSV", func(t *testing.T) {
+		store, err := Open(filepath.Join(t.TempDir(), "history.jsonl"), nil)
+		assert.NoError(t, err)
+		at := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+		assert.NoError(t, store.Append([]Record{
+			{At: at, SKU: "SKU01", Available: true, Price: 400, ComparePrice: 450},
+			{At: at, SKU: "SKU02", Available: false, Price: 90},
+		}))
+
+		var output strings.Builder
+		assert.NoError(t, WriteCSV(&output, store.Export(map[string]bool{"SKU01": true}, at.Add(-time.Hour), at)))
+		assert.Equal(t, "timestamp,sku,available,quantity,price,mrp\n2025-05-01T10:00:00Z,SKU01,true,0,400,450\n", output.String())
+		assert.Equal(t, 2, len(store.Export(nil, at, at)))
+	})
+
+	t.Run("Parse time bounds", func(t *testing.T) {
+		from, err := ParseTimeBound("2025-05-01", false, time.UTC)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC), from)
+
+		to, err := ParseTimeBound("2025-05-01", true, time.UTC)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2025, 5, 1, 23, 59, 59, 999999999, time.UTC), to)
+
+		_, err = ParseTimeBound("yesterday", false, time.UTC)
+		assert.Error(t, err)
+	})
+
+	t.Run("Find the last in-stock run", func(t *testing.T) {
+		store, err := Open(filepath.Join(t.TempDir(), "history.jsonl"), nil)
+		assert.NoError(t, err)
+		at := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+		assert.NoError(t, store.Append([]Record{
+			{At: at, SKU: "SKU01", Available: true, Quantity: 30},
+			{At: at.Add(10 * time.Minute), SKU: "SKU01", Available: false},
+			{At: at.Add(20 * time.Minute), SKU: "SKU01", Available: true, Quantity: 100},
+			{At: at.Add(30 * time.Minute), SKU: "SKU02", Available: false},
+			{At: at.Add(40 * time.Minute), SKU: "SKU01", Available: true, Quantity: 60},
+			{At: at.Add(50 * time.Minute), SKU: "SKU01", Available: true, Quantity: 5},
+		}))
+
+		run, found := store.LastInStockRun("SKU01", at.Add(time.Hour))
+		assert.True(t, found)
+		assert.Equal(t, at.Add(20*time.Minute), run.Start)
+		assert.Equal(t, at.Add(50*time.Minute), run.End)
+		assert.Equal(t, 100, run.PeakQuantity)
+
+		_, found = store.LastInStockRun("SKU02", at.Add(time.Hour))
+		assert.False(t, found)
+	})
+
+	t.Run("Measure restock gaps", func(t *testing.T) {
+		store, err := Open(filepath.Join(t.TempDir(), "history.jsonl"), nil)
+		assert.NoError(t, err)
+		at := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+		assert.NoError(t, store.Append([]Record{
+			{At: at, SKU: "SKU01", Available: false},
+			{At: at.Add(time.Hour), SKU: "SKU01", Available: true},
+			{At: at.Add(2 * time.Hour), SKU: "SKU01", Available: false},
+			{At: at.Add(3 * time.Hour), SKU: "SKU01", Available: false},
+			{At: at.Add(26 * time.Hour), SKU: "SKU01", Available: true},
+			{At: at.Add(27 * time.Hour), SKU: "SKU01", Available: false},
+		}))
+
+		assert.Equal(t, []time.Duration{24 * time.Hour}, store.RestockGaps("SKU01", "gujarat"))
+		assert.Empty(t, store.RestockGaps("SKU02", "gujarat"))
+	})
+
+	t.Run("Measure restock gaps per store", func(t *testing.T) {
+		store, err := Open(filepath.Join(t.TempDir(), "history.jsonl"), nil)
+		assert.NoError(t, err)
+		at := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+		assert.NoError(t, store.Append([]Record{
+			{At: at, SKU: "SKU01", Available: true, Store: "gujarat"},
+			{At: at, SKU: "SKU01", Available: true, Store: "maharashtra"},
+			{At: at.Add(time.Hour), SKU: "SKU01", Available: false, Store: "gujarat"},
+			{At: at.Add(time.Hour), SKU: "SKU01", Available: false, Store: "maharashtra"},
+			{At: at.Add(3 * time.Hour), SKU: "SKU01", Available: true, Store: "gujarat"},
+			{At: at.Add(3 * time.Hour), SKU: "SKU01", Available: false, Store: "maharashtra"},
+			{At: at.Add(11 * time.Hour), SKU: "SKU01", Available: true, Store: "maharashtra"},
+		}))
+
+		assert.Equal(t, []time.Duration{2 * time.Hour}, store.RestockGaps("SKU01", "gujarat"))
+		assert.Equal(t, []time.Duration{10 * time.Hour}, store.RestockGaps("SKU01", "maharashtra"))
+		assert.Empty(t, store.RestockGaps("SKU01", "kerala"))
+	})
+}
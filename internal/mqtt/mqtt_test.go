@@ -0,0 +1,109 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Message received by the fake broker
+type received struct {
+	username, password string
+	topic              string
+	payload            string
+	retained           bool
+}
+
+// startFakeBroker accepts one connection, answering CONNACK with returnCode and acknowledging one publish
+func startFakeBroker(t *testing.T, returnCode byte) (string, <-chan received) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan received, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+
+		var message received
+		_, connect, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+		// Skip the protocol name, level, flags and keep alive, then the client ID
+		rest := connect[10:]
+		_, rest = readString(rest)
+		if len(rest) > 0 {
+			message.username, rest = readString(rest)
+			message.password, _ = readString(rest)
+		}
+		conn.Write([]byte{packetConnack, 2, 0, returnCode})
+		if returnCode != 0 {
+			messages <- message
+			return
+		}
+
+		header, publish, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+		message.retained = header&publishRetain != 0
+		message.topic, rest = readString(publish)
+		packetID := rest[:2]
+		message.payload = string(rest[2:])
+		conn.Write(append([]byte{packetPuback, 2}, packetID...))
+		messages <- message
+	}()
+	return listener.Addr().String(), messages
+}
+
+func readString(buffer []byte) (string, []byte) {
+	length := int(binary.BigEndian.Uint16(buffer))
+	return string(buffer[2 : 2+length]), buffer[2+length:]
+}
+
+func TestPublish(t *testing.T) {
+	t.Run("Publish a retained message with credentials", func(t *testing.T) {
+		addr, messages := startFakeBroker(t, 0)
+		client, err := New("mqtt://home:secret@" + addr)
+		assert.NoError(t, err)
+
+		assert.NoError(t, client.Publish("amul-notifier/LASCP40_30", []byte(`{"in_stock":true}`)))
+		assert.Equal(t, received{
+			username: "home", password: "secret",
+			topic: "amul-notifier/LASCP40_30", payload: `{"in_stock":true}`, retained: true,
+		}, <-messages)
+	})
+
+	t.Run("Report refused connections", func(t *testing.T) {
+		addr, _ := startFakeBroker(t, 5)
+		client, err := New("mqtt://" + addr)
+		assert.NoError(t, err)
+		assert.ErrorContains(t, client.Publish("amul-notifier/LASCP40_30", nil), "code 5")
+	})
+
+	t.Run("Parse broker URLs", func(t *testing.T) {
+		client, err := New("mqtts://broker.example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, "broker.example.com:8883", client.addr)
+		assert.True(t, client.useTLS)
+
+		_, err = New("http://broker.example.com")
+		assert.Error(t, err)
+
+		var nilClient *Client
+		assert.NoError(t, nilClient.Publish("topic", nil))
+	})
+
+	t.Run("Encode long lengths as varints", func(t *testing.T) {
+		encoded := packet(packetPublish, make([]byte, 321))
+		assert.Equal(t, []byte{packetPublish, 0xc1, 0x02}, encoded[:3])
+	})
+}
@@ -0,0 +1,172 @@
+// Package notion keeps a Notion database up to date with the current stock of every monitored SKU
+package notion
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	notionAPIURL  = "https://api.notion.com/v1"
+	notionVersion = "2022-06-28"
+)
+
+// Current stock of one SKU, stored as one database row
+type Row struct {
+	SKU      string
+	Name     string
+	InStock  bool
+	Quantity int
+	Price    int
+}
+
+// Sync upserts one row per SKU into a database. The database needs the properties SKU (title), Name (text),
+// Status (select), Quantity (number), Price (number), Last restock (date) and Updated (date).
+// A nil *Sync syncs nothing.
+type Sync struct {
+	token      string
+	databaseID string
+	apiURL     string
+	httpClient *http.Client
+
+	// SKU -> page ID of its row
+	pageIDs map[string]string
+	// SKU -> row as last written, unchanged rows are skipped
+	synced map[string]Row
+	// SKU -> last time the SKU was seen coming back in stock
+	lastRestocks map[string]time.Time
+}
+
+func New(token, databaseID string) *Sync {
+	return &Sync{
+		token:        token,
+		databaseID:   databaseID,
+		apiURL:       notionAPIURL,
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
+		pageIDs:      make(map[string]string),
+		synced:       make(map[string]Row),
+		lastRestocks: make(map[string]time.Time),
+	}
+}
+
+// SyncRows writes the rows that changed since the last sync, creating rows for new SKUs
+func (s *Sync) SyncRows(rows []Row, at time.Time) error {
+	if s == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, row := range rows {
+		previous, wasSynced := s.synced[row.SKU]
+		if wasSynced && previous == row {
+			continue
+		}
+		// Restocks seen before the first sync are unknown, Notion keeps whatever it had
+		if wasSynced && row.InStock && !previous.InStock {
+			s.lastRestocks[row.SKU] = at
+		}
+
+		if err := s.upsert(row, at); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", row.SKU, err))
+			continue
+		}
+		s.synced[row.SKU] = row
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Sync) upsert(row Row, at time.Time) error {
+	status := "Out of stock"
+	if row.InStock {
+		status = "In stock"
+	}
+	properties := map[string]any{
+		"SKU":      map[string]any{"title": []any{map[string]any{"text": map[string]string{"content": row.SKU}}}},
+		"Name":     map[string]any{"rich_text": []any{map[string]any{"text": map[string]string{"content": row.Name}}}},
+		"Status":   map[string]any{"select": map[string]string{"name": status}},
+		"Quantity": map[string]any{"number": row.Quantity},
+		"Price":    map[string]any{"number": row.Price},
+		"Updated":  map[string]any{"date": map[string]string{"start": at.Format(time.RFC3339)}},
+	}
+	if lastRestock, known := s.lastRestocks[row.SKU]; known {
+		properties["Last restock"] = map[string]any{"date": map[string]string{"start": lastRestock.Format(time.RFC3339)}}
+	}
+
+	pageID, err := s.findPage(row.SKU)
+	if err != nil {
+		return err
+	}
+	if pageID != "" {
+		return s.call(http.MethodPatch, "/pages/"+pageID, map[string]any{"properties": properties}, nil)
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	body := map[string]any{"parent": map[string]string{"database_id": s.databaseID}, "properties": properties}
+	if err := s.call(http.MethodPost, "/pages", body, &created); err != nil {
+		return err
+	}
+	s.pageIDs[row.SKU] = created.ID
+	return nil
+}
+
+// findPage returns the page ID of the row of a SKU, empty when the SKU has no row yet
+func (s *Sync) findPage(sku string) (string, error) {
+	if pageID, cached := s.pageIDs[sku]; cached {
+		return pageID, nil
+	}
+
+	var result struct {
+		Results []struct {
+			ID string `json:"id"`
+		} `json:"results"`
+	}
+	query := map[string]any{"filter": map[string]any{"property": "SKU", "title": map[string]string{"equals": sku}}}
+	if err := s.call(http.MethodPost, "/databases/"+s.databaseID+"/query", query, &result); err != nil {
+		return "", err
+	}
+	if len(result.Results) == 0 {
+		return "", nil
+	}
+	s.pageIDs[sku] = result.Results[0].ID
+	return result.Results[0].ID, nil
+}
+
+// call sends a JSON request to the Notion API, decoding the response into target when it isn't nil
+func (s *Sync) call(method, path string, body, target any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, s.apiURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	req.Header.Set("Notion-Version", notionVersion)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("notion API returned status %d: %s", resp.StatusCode, responseBody)
+	}
+	if target == nil {
+		return nil
+	}
+	return json.Unmarshal(responseBody, target)
+}
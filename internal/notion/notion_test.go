@@ -0,0 +1,76 @@
+package notion
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotionSync(t *testing.T) {
+	t.Run("Upsert one row per SKU and skip unchanged rows", func(t *testing.T) {
+		requests := []string{}
+		lastProperties := map[string]any{}
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /databases/db-id/query", func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, "query")
+			assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+			assert.Equal(t, notionVersion, r.Header.Get("Notion-Version"))
+			var query map[string]any
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&query))
+			if query["filter"].(map[string]any)["title"].(map[string]any)["equals"] == "SKU01" {
+				w.Write([]byte(`{"results":[{"id":"page-1"}]}`))
+				return
+			}
+			w.Write([]byte(`{"results":[]}`))
+		})
+		mux.HandleFunc("POST /pages", func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, "create")
+			w.Write([]byte(`{"id":"page-2"}`))
+		})
+		mux.HandleFunc("PATCH /pages/{id}", func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, "update "+r.PathValue("id"))
+			var body struct {
+				Properties map[string]any `json:"properties"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			lastProperties = body.Properties
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		sync := New("secret", "db-id")
+		sync.apiURL = server.URL
+		at := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+
+		rows := []Row{{SKU: "SKU01", Name: "Rose Lassi"}, {SKU: "SKU02", Name: "Paneer"}}
+		assert.NoError(t, sync.SyncRows(rows, at))
+		assert.NoError(t, sync.SyncRows(rows, at.Add(time.Hour)))
+		assert.Equal(t, []string{"query", "update page-1", "query", "create"}, requests)
+		assert.NotContains(t, lastProperties, "Last restock")
+
+		rows[1].InStock, rows[1].Quantity = true, 12
+		assert.NoError(t, sync.SyncRows(rows, at.Add(2*time.Hour)))
+		assert.Equal(t, "update page-2", requests[len(requests)-1])
+		assert.Equal(t, map[string]any{"date": map[string]any{"start": "2025-05-01T12:00:00Z"}}, lastProperties["Last restock"])
+		assert.Equal(t, 12.0, lastProperties["Quantity"].(map[string]any)["number"])
+	})
+
+	t.Run("Report failures per SKU", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+
+		sync := New("wrong", "db-id")
+		sync.apiURL = server.URL
+		err := sync.SyncRows([]Row{{SKU: "SKU01"}}, time.Now())
+		assert.ErrorContains(t, err, "SKU01: notion API returned status 401")
+
+		var nilSync *Sync
+		assert.NoError(t, nilSync.SyncRows([]Row{{SKU: "SKU01"}}, time.Now()))
+	})
+}
@@ -0,0 +1,434 @@
+package outbox
+
+import (
+	"amul-notifier/internal/storage"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	StatusPending    = "pending"
+	StatusSent       = "sent"
+	StatusDeadLetter = "dead-letter"
+	StatusExpired    = "expired"
+
+	// Sent and expired entries are dropped from the file after this long, dead letters are kept until re-driven
+	retention = 30 * 24 * time.Hour
+
+	// A failed entry is retried after retryBackoff, doubling with every retry up to maxRetryBackoff,
+	// and dead-lettered after maxRetries
+	retryBackoff    = time.Minute
+	maxRetryBackoff = time.Hour
+	maxRetries      = 8
+)
+
+// RetryInterval is how often pending entries should be checked for a due retry, so the shortest backoff is honored
+const RetryInterval = retryBackoff
+
+// A single notification addressed to one chat on one channel
+type Entry struct {
+	ID               int64     `json:"id"`
+	Channel          string    `json:"channel"`
+	ChatID           string    `json:"chat_id"`
+	SKU              string    `json:"sku,omitempty"`
+	NotificationType string    `json:"notification_type"`
+	Message          string    `json:"message"`
+	Silent           bool      `json:"silent,omitempty"`
+	Pin              bool      `json:"pin,omitempty"`
+	ThreadID         int       `json:"thread_id,omitempty"`
+	PhotoURL         string    `json:"photo_url,omitempty"`
+	Status           string    `json:"status"`
+	Attempts         int       `json:"attempts"`
+	LastError        string    `json:"last_error,omitempty"`
+	Retries          int       `json:"retries,omitempty"`
+	NextAttemptAt    time.Time `json:"next_attempt_at,omitzero"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+	DeliveredAt      time.Time `json:"delivered_at,omitzero"`
+	DeadLetteredAt   time.Time `json:"dead_lettered_at,omitzero"`
+	RedrivenAt       time.Time `json:"redriven_at,omitzero"`
+}
+
+// QueuedAt is when the entry last entered the pending queue, either on creation or when re-driven
+func (e Entry) QueuedAt() time.Time {
+	if e.RedrivenAt.After(e.CreatedAt) {
+		return e.RedrivenAt
+	}
+	return e.CreatedAt
+}
+
+// Delivery counts per status
+type Stats map[string]int
+
+// Outbox persists every outgoing notification to an append-only JSON Lines file, with a line for every change of
+// an entry. The file is compacted to the latest version of each entry when opened. A nil *Outbox records nothing.
+type Outbox struct {
+	mu   sync.Mutex
+	path string
+	// AES-256 key used to encrypt each line, nil to store plaintext JSON
+	key     []byte
+	nextID  int64
+	entries []Entry
+}
+
+// Open loads the outbox file, compacting it when it holds superseded or expired lines
+func Open(path string, key []byte) (*Outbox, error) {
+	return load(path, key, true)
+}
+
+// OpenReadOnly loads the outbox file without ever rewriting it, for inspecting it next to the notifier
+func OpenReadOnly(path string, key []byte) (*Outbox, error) {
+	return load(path, key, false)
+}
+
+func load(path string, key []byte, readWrite bool) (*Outbox, error) {
+	o := &Outbox{path: path, key: key, nextID: 1}
+
+	// Outbox files written before it became append-only may be encrypted as a whole
+	data, err := storage.ReadFile(path, key)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("Outbox file %s not found, starting with an empty outbox", path)
+		return o, nil
+	}
+	compact := false
+	if err == nil {
+		o.entries, compact, err = parseEntries(data, key)
+	}
+	if errors.Is(err, storage.ErrCorrupt) {
+		o.entries, err = recoverFromBackup(path, key, readWrite, err)
+		compact = true
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading outbox file: %w", err)
+	}
+
+	for _, entry := range o.entries {
+		o.nextID = max(o.nextID, entry.ID+1)
+	}
+	log.Printf("Loaded %d outbox entries from %s", len(o.entries), path)
+
+	// Changes are only ever appended, so the file is rewritten with just the latest version of each entry
+	if readWrite && compact {
+		if err := o.rewrite(); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// parseEntries decodes the lines of an outbox file into the latest version of each entry within the retention
+// period, and reports whether the file holds lines that compacting it would drop. Files written before the
+// outbox became append-only hold a single JSON array instead.
+func parseEntries(data []byte, key []byte) ([]Entry, bool, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		entries := []Entry{}
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, false, fmt.Errorf("%w: %w", storage.ErrCorrupt, err)
+		}
+		return prune(entries), true, nil
+	}
+
+	entries := []Entry{}
+	positions := make(map[int64]int)
+	lineCount := 0
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		lineCount++
+		var entry Entry
+		data, err := storage.OpenLine(line, key)
+		if err == nil {
+			if jsonErr := json.Unmarshal(data, &entry); jsonErr != nil {
+				err = fmt.Errorf("%w: %w", storage.ErrCorrupt, jsonErr)
+			}
+		}
+		// A crash during an append can cut off the last line, which is skipped instead of treating the whole
+		// file as corrupt
+		if err != nil && i == len(lines)-1 {
+			log.Printf("Warning: Skipping incomplete outbox line %d: %v", i+1, err)
+			continue
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("error reading outbox line %d: %w", i+1, err)
+		}
+
+		if position, exists := positions[entry.ID]; exists {
+			entries[position] = entry
+			continue
+		}
+		positions[entry.ID] = len(entries)
+		entries = append(entries, entry)
+	}
+
+	entries = prune(entries)
+	return entries, lineCount > len(entries), nil
+}
+
+// recoverFromBackup loads the outbox from its last compaction instead of a corrupt file. Read-only outboxes
+// leave the corrupt file in place for the notifier to move aside.
+func recoverFromBackup(path string, key []byte, readWrite bool, corruptErr error) ([]Entry, error) {
+	if readWrite {
+		quarantinePath, err := storage.Quarantine(path)
+		if err != nil {
+			return nil, fmt.Errorf("%w, and moving it aside failed: %w", corruptErr, err)
+		}
+		log.Printf("Warning: Outbox file %s is corrupt (%v), moved it to %s and loading the backup", path, corruptErr, quarantinePath)
+	}
+
+	data, err := storage.ReadBackup(path, key)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("No outbox backup found, starting with an empty outbox")
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("outbox backup is unreadable too: %w", err)
+	}
+	entries, _, err := parseEntries(data, key)
+	if err != nil {
+		return nil, fmt.Errorf("outbox backup is unreadable too: %w", err)
+	}
+	return entries, nil
+}
+
+// prune drops finished entries past the retention period, pending entries and dead letters are always kept
+func prune(entries []Entry) []Entry {
+	cutoff := time.Now().Add(-retention)
+	kept := entries[:0]
+	for _, entry := range entries {
+		if entry.Status == StatusPending || entry.Status == StatusDeadLetter || entry.UpdatedAt.After(cutoff) {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// Add records a new pending entry and returns its ID
+func (o *Outbox) Add(entry Entry) int64 {
+	if o == nil {
+		return 0
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now()
+	entry.ID = o.nextID
+	entry.Status = StatusPending
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+	o.nextID++
+	o.entries = append(prune(o.entries), entry)
+
+	o.append(entry)
+	return entry.ID
+}
+
+// RecordAttempt updates an entry after a delivery attempt. A nil error marks it sent, otherwise it stays pending.
+func (o *Outbox) RecordAttempt(id int64, deliveryErr error) {
+	o.update(id, func(entry *Entry) {
+		entry.Attempts++
+		if deliveryErr != nil {
+			entry.LastError = deliveryErr.Error()
+			return
+		}
+		entry.Status = StatusSent
+		entry.LastError = ""
+		entry.DeliveredAt = entry.UpdatedAt
+	})
+}
+
+// RetryLater keeps a failed entry pending until its next retry, backing off exponentially. It returns the time
+// of the next retry, or false once the entry used up its retries and should be dead-lettered.
+func (o *Outbox) RetryLater(id int64) (time.Time, bool) {
+	var nextAttemptAt time.Time
+	o.update(id, func(entry *Entry) {
+		if entry.Retries >= maxRetries {
+			return
+		}
+		entry.Retries++
+		entry.NextAttemptAt = entry.UpdatedAt.Add(backoff(entry.Retries))
+		nextAttemptAt = entry.NextAttemptAt
+	})
+	return nextAttemptAt, !nextAttemptAt.IsZero()
+}
+
+// backoff is the wait before the given retry, counting from 1
+func backoff(retry int) time.Duration {
+	return min(retryBackoff<<(retry-1), maxRetryBackoff)
+}
+
+// MaxRetryAge is how long after being queued an entry is still worth retrying: twice its whole retry schedule,
+// leaving room for retries delayed by quiet hours or a restart before it is given up on as stale
+func MaxRetryAge() time.Duration {
+	var schedule time.Duration
+	for retry := 1; retry <= maxRetries; retry++ {
+		schedule += backoff(retry)
+	}
+	return 2 * schedule
+}
+
+// MarkDeadLetter parks an entry whose retries are exhausted, keeping the last error as the reason
+func (o *Outbox) MarkDeadLetter(id int64) {
+	o.update(id, func(entry *Entry) {
+		entry.Status = StatusDeadLetter
+		entry.DeadLetteredAt = entry.UpdatedAt
+	})
+}
+
+// MarkExpired gives up on a pending entry that is too old to be worth delivering
+func (o *Outbox) MarkExpired(id int64) {
+	o.update(id, func(entry *Entry) { entry.Status = StatusExpired })
+}
+
+// Pending returns copies of all entries still waiting for delivery, oldest first
+func (o *Outbox) Pending() []Entry {
+	if o == nil {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.withStatus(StatusPending)
+}
+
+// Due returns copies of the pending entries whose next retry is due, oldest first
+func (o *Outbox) Due(now time.Time) []Entry {
+	due := []Entry{}
+	for _, entry := range o.Pending() {
+		if !entry.NextAttemptAt.After(now) {
+			due = append(due, entry)
+		}
+	}
+	return due
+}
+
+// DeadLetters returns copies of all entries that exhausted their retries, oldest first
+func (o *Outbox) DeadLetters() []Entry {
+	if o == nil {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.withStatus(StatusDeadLetter)
+}
+
+// Redrive moves every dead letter back to the pending queue and returns how many were moved
+func (o *Outbox) Redrive() int {
+	if o == nil {
+		return 0
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now()
+	redriven := []Entry{}
+	for i := range o.entries {
+		if o.entries[i].Status == StatusDeadLetter {
+			o.entries[i].Status = StatusPending
+			o.entries[i].RedrivenAt = now
+			o.entries[i].Retries = 0
+			o.entries[i].NextAttemptAt = time.Time{}
+			o.entries[i].UpdatedAt = now
+			redriven = append(redriven, o.entries[i])
+		}
+	}
+	if len(redriven) > 0 {
+		o.append(redriven...)
+	}
+	return len(redriven)
+}
+
+// withStatus returns copies of the entries in the given status, callers must hold the lock
+func (o *Outbox) withStatus(status string) []Entry {
+	matching := []Entry{}
+	for _, entry := range o.entries {
+		if entry.Status == status {
+			matching = append(matching, entry)
+		}
+	}
+	return matching
+}
+
+func (o *Outbox) Stats() Stats {
+	stats := Stats{}
+	if o == nil {
+		return stats
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, entry := range o.entries {
+		stats[entry.Status]++
+	}
+	return stats
+}
+
+func (o *Outbox) update(id int64, apply func(entry *Entry)) {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i := range o.entries {
+		if o.entries[i].ID == id {
+			o.entries[i].UpdatedAt = time.Now()
+			apply(&o.entries[i])
+			o.append(o.entries[i])
+			return
+		}
+	}
+	log.Printf("Warning: Outbox entry %d not found", id)
+}
+
+// append writes the latest version of the given entries to the end of the outbox file, callers must hold the lock
+func (o *Outbox) append(entries ...Entry) {
+	lines, err := encodeLines(entries, o.key)
+	if err != nil {
+		log.Printf("Error marshalling outbox entry: %v", err)
+		return
+	}
+	if err := storage.AppendFile(o.path, []byte(lines)); err != nil {
+		log.Printf("Error writing outbox file %s: %v", o.path, err)
+	}
+}
+
+// rewrite replaces the outbox file with the entries held in memory, keeping the previous file as the backup.
+// Callers must hold the lock or own the outbox. Lines are sealed one by one, so the file itself is written unencrypted.
+func (o *Outbox) rewrite() error {
+	lines, err := encodeLines(o.entries, o.key)
+	if err != nil {
+		return err
+	}
+	if err := storage.WriteFile(o.path, []byte(lines), nil); err != nil {
+		return fmt.Errorf("error rewriting outbox file: %w", err)
+	}
+	return nil
+}
+
+func encodeLines(entries []Entry, key []byte) (string, error) {
+	var lines strings.Builder
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return "", fmt.Errorf("error marshalling outbox entry: %w", err)
+		}
+		line, err := storage.SealLine(data, key)
+		if err != nil {
+			return "", err
+		}
+		lines.WriteString(line)
+		lines.WriteString("\n")
+	}
+	return lines.String(), nil
+}
@@ -0,0 +1,164 @@
+package outbox
+
+import (
+	"amul-notifier/internal/storage"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutbox(t *testing.T) {
+	t.Run("Persist entries and statuses", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.json")
+		o, err := Open(path, nil)
+		assert.NoError(t, err)
+
+		sentID := o.Add(Entry{Channel: "telegram", ChatID: "1", SKU: "SKU01", NotificationType: "in-stock", Message: "hi"})
+		o.RecordAttempt(sentID, errors.New("timeout"))
+		o.RecordAttempt(sentID, nil)
+		pendingID := o.Add(Entry{Channel: "telegram", ChatID: "2", SKU: "SKU02", NotificationType: "out-of-stock", Message: "bye"})
+
+		reopened, err := Open(path, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, Stats{StatusSent: 1, StatusPending: 1}, reopened.Stats())
+
+		pending := reopened.Pending()
+		assert.Equal(t, 1, len(pending))
+		assert.Equal(t, pendingID, pending[0].ID)
+		assert.Equal(t, pendingID+1, reopened.Add(Entry{ChatID: "3"}))
+	})
+
+	t.Run("Dead letters are kept and can be re-driven", func(t *testing.T) {
+		o, err := Open(filepath.Join(t.TempDir(), "outbox.json"), nil)
+		assert.NoError(t, err)
+
+		id := o.Add(Entry{ChatID: "1", NotificationType: "in-stock"})
+		o.RecordAttempt(id, errors.New("Forbidden: bot was blocked by the user"))
+		o.MarkDeadLetter(id)
+
+		deadLetters := o.DeadLetters()
+		assert.Equal(t, 1, len(deadLetters))
+		assert.Equal(t, "Forbidden: bot was blocked by the user", deadLetters[0].LastError)
+		assert.Empty(t, o.Pending())
+
+		assert.Equal(t, 1, o.Redrive())
+		pending := o.Pending()
+		assert.Equal(t, 1, len(pending))
+		assert.Equal(t, pending[0].RedrivenAt, pending[0].QueuedAt())
+		assert.Empty(t, o.DeadLetters())
+	})
+
+	t.Run("Retry failed entries with exponential backoff", func(t *testing.T) {
+		o, err := Open(filepath.Join(t.TempDir(), "outbox.json"), nil)
+		assert.NoError(t, err)
+
+		id := o.Add(Entry{ChatID: "1", NotificationType: "in-stock"})
+		o.RecordAttempt(id, errors.New("Bad Gateway"))
+		assert.Len(t, o.Due(time.Now()), 1)
+
+		backoffs := []time.Duration{}
+		for {
+			nextAttemptAt, retry := o.RetryLater(id)
+			if !retry {
+				break
+			}
+			backoffs = append(backoffs, nextAttemptAt.Sub(o.Pending()[0].UpdatedAt))
+		}
+		assert.Equal(t, []time.Duration{
+			time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 16 * time.Minute, 32 * time.Minute, time.Hour, time.Hour,
+		}, backoffs)
+		assert.Empty(t, o.Due(time.Now()))
+		assert.Len(t, o.Due(time.Now().Add(time.Hour)), 1)
+
+		var schedule time.Duration
+		for _, wait := range backoffs {
+			schedule += wait
+		}
+		assert.Greater(t, MaxRetryAge(), schedule)
+
+		// Re-driving starts the retries over
+		o.MarkDeadLetter(id)
+		o.Redrive()
+		assert.Len(t, o.Due(time.Now()), 1)
+		_, retry := o.RetryLater(id)
+		assert.True(t, retry)
+	})
+
+	t.Run("Recover a corrupt file from the backup", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.json")
+		o, err := Open(path, nil)
+		assert.NoError(t, err)
+		o.RecordAttempt(o.Add(Entry{ChatID: "1", NotificationType: "in-stock"}), errors.New("Bad Gateway"))
+		// Compacting on open keeps the previous file as the backup
+		o, err = Open(path, nil)
+		assert.NoError(t, err)
+		o.Add(Entry{ChatID: "2", NotificationType: "in-stock"})
+		assert.NoError(t, os.WriteFile(path, []byte("{\"id\": 1, \"chat\n{\"id\": 2}\n"), 0o600))
+
+		recovered, err := Open(path, nil)
+		assert.NoError(t, err)
+		pending := recovered.Pending()
+		assert.Equal(t, 1, len(pending))
+		assert.Equal(t, "1", pending[0].ChatID)
+
+		quarantined, err := filepath.Glob(path + ".corrupt-*")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(quarantined))
+	})
+
+	t.Run("Append changes and compact them on open", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.json")
+		key := storage.DeriveKey("secret")
+		o, err := Open(path, key)
+		assert.NoError(t, err)
+
+		id := o.Add(Entry{ChatID: "1", NotificationType: "in-stock", Message: "Rose Lassi is back"})
+		o.RecordAttempt(id, errors.New("Bad Gateway"))
+		o.RecordAttempt(id, nil)
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, strings.Count(string(data), "\n"))
+		assert.NotContains(t, string(data), "Rose Lassi")
+
+		// A crash cutting off the last append only loses that change
+		assert.NoError(t, storage.AppendFile(path, []byte(`{"id": 1, "sta`)))
+
+		reopened, err := Open(path, key)
+		assert.NoError(t, err)
+		assert.Equal(t, Stats{StatusSent: 1}, reopened.Stats())
+		data, err = os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(string(data), "\n"))
+
+		readOnly, err := OpenReadOnly(path, key)
+		assert.NoError(t, err)
+		assert.Equal(t, Stats{StatusSent: 1}, readOnly.Stats())
+	})
+
+	t.Run("Migrate an outbox written as a single JSON array", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "outbox.json")
+		assert.NoError(t, os.WriteFile(path, []byte(`[{"id": 4, "chat_id": "1", "status": "pending"}, {"id": 5, "chat_id": "2", "status": "dead-letter"}]`), 0o600))
+
+		o, err := Open(path, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, Stats{StatusPending: 1, StatusDeadLetter: 1}, o.Stats())
+		assert.Equal(t, int64(6), o.Add(Entry{ChatID: "3"}))
+
+		reopened, err := Open(path, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, Stats{StatusPending: 2, StatusDeadLetter: 1}, reopened.Stats())
+	})
+
+	t.Run("Nil outbox records nothing", func(t *testing.T) {
+		var o *Outbox
+		assert.Equal(t, int64(0), o.Add(Entry{}))
+		o.RecordAttempt(1, nil)
+		assert.Empty(t, o.Pending())
+		assert.Empty(t, o.Stats())
+	})
+}
@@ -0,0 +1,85 @@
+// Package pushover sends notifications through the Pushover API
+package pushover
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	pushoverAPIURL = "https://api.pushover.net/1/messages.json"
+
+	// Pushover rejects longer messages
+	maxMessageLength = 1024
+)
+
+// Message priorities, see https://pushover.net/api#priority
+const (
+	PriorityQuiet  = -1 // no sound or vibration
+	PriorityNormal = 0
+	PriorityHigh   = 1 // bypasses the user's quiet hours
+)
+
+// Client sends to one user or group. A nil *Client sends nothing.
+type Client struct {
+	appToken   string
+	userKey    string
+	apiURL     string
+	httpClient *http.Client
+}
+
+func New(appToken, userKey string) *Client {
+	return &Client{
+		appToken:   appToken,
+		userKey:    userKey,
+		apiURL:     pushoverAPIURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Send delivers a message written in Pushover's HTML subset (<b>, <i>, <u>, <a>), cut to the length limit
+func (c *Client) Send(title, message string, priority int) error {
+	if c == nil {
+		return nil
+	}
+
+	if len(message) > maxMessageLength {
+		message = message[:maxMessageLength-len("…")]
+		for !utf8.ValidString(message) {
+			message = message[:len(message)-1]
+		}
+		message += "…"
+	}
+	resp, err := c.httpClient.PostForm(c.apiURL, url.Values{
+		"token":    {c.appToken},
+		"user":     {c.userKey},
+		"title":    {title},
+		"message":  {message},
+		"html":     {"1"},
+		"priority": {strconv.Itoa(priority)},
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var response struct {
+		Status int      `json:"status"`
+		Errors []string `json:"errors"`
+	}
+	json.Unmarshal(body, &response)
+	if resp.StatusCode != http.StatusOK || response.Status != 1 {
+		return fmt.Errorf("pushover returned status %d: %v", resp.StatusCode, response.Errors)
+	}
+	return nil
+}
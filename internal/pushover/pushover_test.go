@@ -0,0 +1,50 @@
+package pushover
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSend(t *testing.T) {
+	t.Run("Send an HTML message with its priority", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.NoError(t, r.ParseForm())
+			assert.Equal(t, "app-token", r.PostForm.Get("token"))
+			assert.Equal(t, "user-key", r.PostForm.Get("user"))
+			assert.Equal(t, "<b>Stock Available!</b>", r.PostForm.Get("message"))
+			assert.Equal(t, "1", r.PostForm.Get("html"))
+			assert.Equal(t, "1", r.PostForm.Get("priority"))
+			w.Write([]byte(`{"status":1,"request":"abc"}`))
+		}))
+		defer server.Close()
+
+		client := New("app-token", "user-key")
+		client.apiURL = server.URL
+		assert.NoError(t, client.Send("Amul Stock Notifier", "<b>Stock Available!</b>", PriorityHigh))
+	})
+
+	t.Run("Cut long messages and report API errors", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.NoError(t, r.ParseForm())
+			message := r.PostForm.Get("message")
+			assert.LessOrEqual(t, len(message), maxMessageLength)
+			assert.True(t, utf8.ValidString(message))
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":0,"errors":["user identifier is invalid"]}`))
+		}))
+		defer server.Close()
+
+		client := New("app-token", "bad-user")
+		client.apiURL = server.URL
+		err := client.Send("Amul Stock Notifier", strings.Repeat("✅", 600), PriorityNormal)
+		assert.ErrorContains(t, err, "user identifier is invalid")
+
+		var nilClient *Client
+		assert.NoError(t, nilClient.Send("title", "message", PriorityNormal))
+	})
+}
@@ -0,0 +1,194 @@
+// Package sheets appends stock observations to a Google Sheet using service-account credentials
+package sheets
+
+import (
+	"amul-notifier/internal/history"
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	sheetsAPIURL = "https://sheets.googleapis.com/v4/spreadsheets"
+	sheetsScope  = "https://www.googleapis.com/auth/spreadsheets"
+
+	// Access tokens are renewed this long before they expire
+	tokenRefreshMargin = time.Minute
+)
+
+// Fields of a service-account key file downloaded from the Google Cloud console
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// Sync appends rows to one sheet of a spreadsheet. A nil *Sync appends nothing.
+type Sync struct {
+	spreadsheetID string
+	// A1 range whose table rows are appended to, e.g. "Sheet1!A:F"
+	sheetRange string
+	apiURL     string
+	account    serviceAccount
+	privateKey *rsa.PrivateKey
+	httpClient *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	tokenExpiry time.Time
+}
+
+// Open reads the service-account key file. The spreadsheet must be shared with the service account's email.
+func Open(credentialsFile, spreadsheetID, sheetRange string) (*Sync, error) {
+	content, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading service account file: %w", err)
+	}
+
+	var account serviceAccount
+	if err := json.Unmarshal(content, &account); err != nil {
+		return nil, fmt.Errorf("error parsing service account file: %w", err)
+	}
+	if account.ClientEmail == "" || account.PrivateKey == "" || account.TokenURI == "" {
+		return nil, errors.New("service account file is missing client_email, private_key or token_uri")
+	}
+
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return nil, errors.New("service account private key is not PEM encoded")
+	}
+	parsedKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing service account private key: %w", err)
+	}
+	privateKey, isRSA := parsedKey.(*rsa.PrivateKey)
+	if !isRSA {
+		return nil, errors.New("service account private key is not an RSA key")
+	}
+
+	return &Sync{
+		spreadsheetID: spreadsheetID,
+		sheetRange:    sheetRange,
+		apiURL:        sheetsAPIURL,
+		account:       account,
+		privateKey:    privateKey,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// AppendRecords adds one row per record below the existing rows, in the same columns as the CSV export
+func (s *Sync) AppendRecords(records []history.Record) error {
+	if s == nil || len(records) == 0 {
+		return nil
+	}
+
+	rows := make([][]string, 0, len(records))
+	for _, record := range records {
+		rows = append(rows, record.Fields())
+	}
+	body, err := json.Marshal(map[string]any{"values": rows})
+	if err != nil {
+		return err
+	}
+
+	token, err := s.token()
+	if err != nil {
+		return err
+	}
+
+	// USER_ENTERED lets Sheets parse timestamps, numbers and booleans for pivot tables
+	appendURL := fmt.Sprintf("%s/%s/values/%s:append?valueInputOption=USER_ENTERED&insertDataOption=INSERT_ROWS",
+		s.apiURL, url.PathEscape(s.spreadsheetID), url.PathEscape(s.sheetRange))
+	req, err := http.NewRequest(http.MethodPost, appendURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		responseBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("sheets append failed with status %d: %s", resp.StatusCode, responseBody)
+	}
+	return nil
+}
+
+// token returns a cached access token, exchanging a freshly signed JWT for a new one when it is about to expire
+func (s *Sync) token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessToken != "" && time.Now().Add(tokenRefreshMargin).Before(s.tokenExpiry) {
+		return s.accessToken, nil
+	}
+
+	assertion, err := s.signedJWT(time.Now())
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.httpClient.PostForm(s.account.TokenURI, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, responseBody)
+	}
+
+	var tokenResponse struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(responseBody, &tokenResponse); err != nil {
+		return "", fmt.Errorf("error parsing token response: %w", err)
+	}
+	s.accessToken = tokenResponse.AccessToken
+	s.tokenExpiry = time.Now().Add(time.Duration(tokenResponse.ExpiresIn) * time.Second)
+	return s.accessToken, nil
+}
+
+// signedJWT builds the RS256 assertion of the service-account OAuth flow
+func (s *Sync) signedJWT(issuedAt time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"iss":   s.account.ClientEmail,
+		"scope": sheetsScope,
+		"aud":   s.account.TokenURI,
+		"iat":   issuedAt.Unix(),
+		"exp":   issuedAt.Add(time.Hour).Unix(),
+	})
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("error signing token request: %w", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
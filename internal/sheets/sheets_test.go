@@ -0,0 +1,72 @@
+package sheets
+
+import (
+	"amul-notifier/internal/history"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSheetsSync(t *testing.T) {
+	t.Run("Append records with a service account token", func(t *testing.T) {
+		privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.NoError(t, err)
+		keyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+		assert.NoError(t, err)
+
+		tokenRequests := 0
+		appendedRows := [][]string{}
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+			tokenRequests++
+			assert.Equal(t, "urn:ietf:params:oauth:grant-type:jwt-bearer", r.FormValue("grant_type"))
+			assert.NotEmpty(t, r.FormValue("assertion"))
+			w.Write([]byte(`{"access_token":"token-1","expires_in":3600}`))
+		})
+		mux.HandleFunc("POST /sheet-id/values/", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer token-1", r.Header.Get("Authorization"))
+			assert.Equal(t, "/sheet-id/values/Stock!A:F:append", r.URL.Path)
+			var body struct {
+				Values [][]string `json:"values"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			appendedRows = append(appendedRows, body.Values...)
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		credentialsFile := filepath.Join(t.TempDir(), "service-account.json")
+		credentials, _ := json.Marshal(map[string]string{
+			"client_email": "notifier@project.iam.gserviceaccount.com",
+			"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes})),
+			"token_uri":    server.URL + "/token",
+		})
+		assert.NoError(t, os.WriteFile(credentialsFile, credentials, 0o600))
+
+		sync, err := Open(credentialsFile, "sheet-id", "Stock!A:F")
+		assert.NoError(t, err)
+		sync.apiURL = server.URL
+
+		at := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+		assert.NoError(t, sync.AppendRecords([]history.Record{{At: at, SKU: "SKU01", Available: true, Quantity: 12, Price: 400}}))
+		assert.NoError(t, sync.AppendRecords([]history.Record{{At: at, SKU: "SKU02"}}))
+		assert.Equal(t, [][]string{
+			{"2025-05-01T10:00:00Z", "SKU01", "true", "12", "400", "400"},
+			{"2025-05-01T10:00:00Z", "SKU02", "false", "0", "0", "0"},
+		}, appendedRows)
+		assert.Equal(t, 1, tokenRequests)
+
+		var nilSync *Sync
+		assert.NoError(t, nilSync.AppendRecords([]history.Record{{SKU: "SKU01"}}))
+	})
+}
@@ -0,0 +1,108 @@
+// Package sms sends text messages through Twilio or MSG91
+package sms
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	twilioAPIURL = "https://api.twilio.com/2010-04-01"
+	msg91APIURL  = "https://control.msg91.com/api/v5/flow"
+)
+
+// Client sends through one provider. A nil *Client sends nothing.
+type Client struct {
+	provider string
+	// Twilio account SID and auth token, or MSG91 auth key in secret
+	accountSID string
+	secret     string
+	// Twilio sender number, or MSG91 flow template ID
+	sender     string
+	apiURL     string
+	httpClient *http.Client
+}
+
+// NewTwilio sends from a Twilio phone number, e.g. +14155550123
+func NewTwilio(accountSID, authToken, fromNumber string) *Client {
+	return &Client{
+		provider:   "twilio",
+		accountSID: accountSID,
+		secret:     authToken,
+		sender:     fromNumber,
+		apiURL:     twilioAPIURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// NewMSG91 sends with a DLT approved MSG91 flow template, which receives the text in its ##message## variable
+func NewMSG91(authKey, templateID string) *Client {
+	return &Client{
+		provider:   "msg91",
+		secret:     authKey,
+		sender:     templateID,
+		apiURL:     msg91APIURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Send delivers a plain text message to a phone number in international format without the +, e.g. 919876543210
+func (c *Client) Send(to, message string) error {
+	if c == nil {
+		return nil
+	}
+
+	var req *http.Request
+	var err error
+	if c.provider == "twilio" {
+		form := url.Values{"To": {"+" + to}, "From": {c.sender}, "Body": {message}}
+		req, err = http.NewRequest(http.MethodPost, c.apiURL+"/Accounts/"+c.accountSID+"/Messages.json", strings.NewReader(form.Encode()))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(c.accountSID, c.secret)
+	} else {
+		payload, err := json.Marshal(map[string]any{
+			"template_id": c.sender,
+			"recipients":  []map[string]string{{"mobiles": to, "message": message}},
+		})
+		if err != nil {
+			return err
+		}
+		req, err = http.NewRequest(http.MethodPost, c.apiURL, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("authkey", c.secret)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	var response struct {
+		// Twilio
+		Message string `json:"message"`
+		// MSG91, which answers 200 with type "error" for rejected requests
+		Type string `json:"type"`
+	}
+	json.Unmarshal(body, &response)
+	if resp.StatusCode >= 300 || response.Type == "error" {
+		return fmt.Errorf("%s returned status %d: %s", c.provider, resp.StatusCode, response.Message)
+	}
+	return nil
+}
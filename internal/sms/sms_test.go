@@ -0,0 +1,65 @@
+package sms
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSend(t *testing.T) {
+	t.Run("Send through Twilio", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/Accounts/AC123/Messages.json", r.URL.Path)
+			user, password, _ := r.BasicAuth()
+			assert.Equal(t, "AC123", user)
+			assert.Equal(t, "auth-token", password)
+			assert.NoError(t, r.ParseForm())
+			assert.Equal(t, "+919876543210", r.PostForm.Get("To"))
+			assert.Equal(t, "+14155550123", r.PostForm.Get("From"))
+			assert.Equal(t, "Amul: Rose Lassi is IN STOCK", r.PostForm.Get("Body"))
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"sid":"SM123","status":"queued"}`))
+		}))
+		defer server.Close()
+
+		client := NewTwilio("AC123", "auth-token", "+14155550123")
+		client.apiURL = server.URL
+		assert.NoError(t, client.Send("919876543210", "Amul: Rose Lassi is IN STOCK"))
+	})
+
+	t.Run("Send through MSG91", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "auth-key", r.Header.Get("authkey"))
+			var payload struct {
+				TemplateID string              `json:"template_id"`
+				Recipients []map[string]string `json:"recipients"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			assert.Equal(t, "template-id", payload.TemplateID)
+			assert.Equal(t, []map[string]string{{"mobiles": "919876543210", "message": "Amul: Rose Lassi is IN STOCK"}}, payload.Recipients)
+			w.Write([]byte(`{"type":"success","message":"3763646c3058373530393831"}`))
+		}))
+		defer server.Close()
+
+		client := NewMSG91("auth-key", "template-id")
+		client.apiURL = server.URL
+		assert.NoError(t, client.Send("919876543210", "Amul: Rose Lassi is IN STOCK"))
+	})
+
+	t.Run("Report API errors", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"type":"error","message":"Invalid template id"}`))
+		}))
+		defer server.Close()
+
+		client := NewMSG91("auth-key", "bad-template")
+		client.apiURL = server.URL
+		assert.ErrorContains(t, client.Send("919876543210", "message"), "Invalid template id")
+
+		var nilClient *Client
+		assert.NoError(t, nilClient.Send("919876543210", "message"))
+	})
+}
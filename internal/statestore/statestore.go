@@ -0,0 +1,125 @@
+// Package statestore keeps the bot's in-memory state in Redis, so a restarted container picks up
+// where the previous one stopped instead of alerting again for everything
+package statestore
+
+import (
+	"amul-notifier/internal/storage"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// State the bot needs to tell changes apart from what it already notified about
+type State struct {
+	// SKU -> inStock
+	StockState map[string]bool `json:"stock_state"`
+	// SKU -> onOffer
+	OfferState map[string]bool `json:"offer_state,omitempty"`
+	// SKU -> below its low-stock threshold
+	LowStockState map[string]bool `json:"low_stock_state,omitempty"`
+	// Local date of the last daily briefing, e.g. 2025-05-01
+	LastBriefingDate string `json:"last_briefing_date,omitempty"`
+	// Local date of the last weekly digest
+	LastWeeklyDigestDate string `json:"last_weekly_digest_date,omitempty"`
+	// Chat ID -> when the chat blocked or removed the bot
+	InactiveChats map[string]time.Time `json:"inactive_chats,omitempty"`
+	// SKU -> consecutive checks the SKU was missing from the API response
+	MissingChecks map[string]int `json:"missing_checks,omitempty"`
+	// SKU/notification type -> when the stock-change alert was last sent, for the dedupe window
+	LastAlerts map[string]time.Time `json:"last_alerts,omitempty"`
+	// Alerts suppressed during quiet hours, sent as a summary once they are over
+	SuppressedAlerts []SuppressedAlert `json:"suppressed_alerts,omitempty"`
+	// Month the SMS count belongs to, e.g. 2025-05, and SMS sent to each recipient that month
+	SMSMonth string    `json:"sms_month,omitempty"`
+	SMSSent  int       `json:"sms_sent,omitempty"`
+	SavedAt  time.Time `json:"saved_at"`
+}
+
+// SuppressedAlert is an alert that wasn't sent because of quiet hours
+type SuppressedAlert struct {
+	At   time.Time `json:"at"`
+	SKU  string    `json:"sku"`
+	Type string    `json:"type"`
+}
+
+// Store saves the state as one JSON value under a key. A nil *Store loads and saves nothing.
+type Store struct {
+	client *redis.Client
+	key    string
+	// AES-256 key used to encrypt the value, nil to store plaintext JSON
+	encryptionKey []byte
+}
+
+// Open connects to a redis:// or rediss:// URL, e.g. redis://:password@localhost:6379/0
+func Open(redisURL, key string, encryptionKey []byte) (*Store, error) {
+	options, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Redis URL: %w", err)
+	}
+	client := redis.NewClient(options)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("error connecting to Redis: %w", err)
+	}
+	return &Store{client: client, key: key, encryptionKey: encryptionKey}, nil
+}
+
+// Load returns the saved state, reporting false when nothing was saved yet
+func (s *Store) Load(ctx context.Context) (State, bool, error) {
+	if s == nil {
+		return State{}, false, nil
+	}
+
+	value, err := s.client.Get(ctx, s.key).Result()
+	if errors.Is(err, redis.Nil) {
+		return State{}, false, nil
+	}
+	if err != nil {
+		return State{}, false, fmt.Errorf("error reading state from Redis: %w", err)
+	}
+	data, err := storage.OpenLine(value, s.encryptionKey)
+	if err != nil {
+		return State{}, false, fmt.Errorf("error decrypting state from Redis: %w", err)
+	}
+
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return State{}, false, fmt.Errorf("error parsing state from Redis: %w", err)
+	}
+	return state, true, nil
+}
+
+// Save replaces the saved state
+func (s *Store) Save(ctx context.Context, state State) error {
+	if s == nil {
+		return nil
+	}
+
+	state.SavedAt = time.Now()
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	value, err := storage.SealLine(data, s.encryptionKey)
+	if err != nil {
+		return err
+	}
+	if err := s.client.Set(ctx, s.key, value, 0).Err(); err != nil {
+		return fmt.Errorf("error writing state to Redis: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+	return s.client.Close()
+}
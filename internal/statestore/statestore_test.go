@@ -0,0 +1,184 @@
+package statestore
+
+import (
+	"amul-notifier/internal/storage"
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRedis answers PING, GET and SET over RESP2 and rejects every other command, like an old Redis would
+type fakeRedis struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func startFakeRedis(t *testing.T) (*fakeRedis, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeRedis{values: make(map[string]string)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server, "redis://" + listener.Addr().String() + "/0"
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		f.mu.Lock()
+		switch strings.ToUpper(args[0]) {
+		case "PING":
+			fmt.Fprint(conn, "+PONG\r\n")
+		case "GET":
+			if value, exists := f.values[args[1]]; exists {
+				fmt.Fprintf(conn, "$%d\r\n%s\r\n", len(value), value)
+			} else {
+				fmt.Fprint(conn, "$-1\r\n")
+			}
+		case "SET":
+			f.values[args[1]] = args[2]
+			fmt.Fprint(conn, "+OK\r\n")
+		default:
+			fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+		}
+		f.mu.Unlock()
+	}
+}
+
+// readCommand reads one command sent as an array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, count)
+	for i := range args {
+		if _, err := reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func TestStore(t *testing.T) {
+	t.Run("Save and load state", func(t *testing.T) {
+		_, redisURL := startFakeRedis(t)
+		store, err := Open(redisURL, "amul-notifier:state", nil)
+		assert.NoError(t, err)
+		defer store.Close()
+
+		_, found, err := store.Load(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, found)
+
+		blockedAt := time.Date(2025, 5, 1, 9, 30, 0, 0, time.UTC)
+		assert.NoError(t, store.Save(context.Background(), State{
+			StockState:       map[string]bool{"LASCP61_30": true, "LASCP40_30": false},
+			OfferState:       map[string]bool{"LASCP61_30": true},
+			LastBriefingDate: "2025-05-01",
+			InactiveChats:    map[string]time.Time{"987654321": blockedAt},
+		}))
+
+		state, found, err := store.Load(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, map[string]bool{"LASCP61_30": true, "LASCP40_30": false}, state.StockState)
+		assert.Equal(t, map[string]bool{"LASCP61_30": true}, state.OfferState)
+		assert.Equal(t, "2025-05-01", state.LastBriefingDate)
+		assert.True(t, blockedAt.Equal(state.InactiveChats["987654321"]))
+		assert.False(t, state.SavedAt.IsZero())
+	})
+
+	t.Run("Encrypt state with a key", func(t *testing.T) {
+		server, redisURL := startFakeRedis(t)
+		key := storage.DeriveKey("state key")
+		store, err := Open(redisURL, "amul-notifier:state", key)
+		assert.NoError(t, err)
+		defer store.Close()
+
+		assert.NoError(t, store.Save(context.Background(), State{InactiveChats: map[string]time.Time{"987654321": time.Now()}}))
+		assert.NotContains(t, server.values["amul-notifier:state"], "987654321")
+		assert.NotContains(t, server.values["amul-notifier:state"], "inactive_chats")
+
+		state, found, err := store.Load(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Contains(t, state.InactiveChats, "987654321")
+
+		withoutKey, err := Open(redisURL, "amul-notifier:state", nil)
+		assert.NoError(t, err)
+		defer withoutKey.Close()
+		_, _, err = withoutKey.Load(context.Background())
+		assert.Error(t, err)
+	})
+
+	t.Run("Plaintext state stays readable with a key", func(t *testing.T) {
+		server, redisURL := startFakeRedis(t)
+		server.values["amul-notifier:state"] = `{"stock_state":{"LASCP61_30":true}}`
+		store, err := Open(redisURL, "amul-notifier:state", storage.DeriveKey("state key"))
+		assert.NoError(t, err)
+		defer store.Close()
+
+		state, _, err := store.Load(context.Background())
+		assert.NoError(t, err)
+		assert.True(t, state.StockState["LASCP61_30"])
+	})
+
+	t.Run("Reject corrupt state", func(t *testing.T) {
+		server, redisURL := startFakeRedis(t)
+		server.values["amul-notifier:state"] = "{not json"
+		store, err := Open(redisURL, "amul-notifier:state", nil)
+		assert.NoError(t, err)
+		defer store.Close()
+
+		_, _, err = store.Load(context.Background())
+		assert.Error(t, err)
+	})
+
+	t.Run("Nil store does nothing", func(t *testing.T) {
+		var store *Store
+		_, found, err := store.Load(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, found)
+		assert.NoError(t, store.Save(context.Background(), State{}))
+		assert.NoError(t, store.Close())
+	})
+
+	t.Run("Reject URLs that are not Redis URLs", func(t *testing.T) {
+		_, err := Open("http://localhost:6379", "amul-notifier:state", nil)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package storage
+
+// lockFile is a no-op where flock isn't available, writes are still atomic but not serialized between processes
+func lockFile(path string, exclusive bool) (func(), error) {
+	return func() {}, nil
+}
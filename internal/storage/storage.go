@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Prefix marking a file written with encryption, followed by the GCM nonce and the ciphertext
+var encryptedFileMagic = []byte("AMULENC1")
+
+// ErrCorrupt is returned when a state file is damaged, e.g. truncated by a crash, and callers
+// that fail to parse a file should treat it the same way
+var ErrCorrupt = errors.New("file is corrupt")
+
+// DeriveKey turns an operator supplied secret into a 32 byte AES-256 key
+func DeriveKey(secret string) []byte {
+	key := sha256.Sum256([]byte(secret))
+	return key[:]
+}
+
+// ReadFile reads a state file, decrypting it when it was written encrypted.
+// Plaintext files are still readable with a key set, so enabling encryption migrates them on the next write.
+func ReadFile(path string, key []byte) ([]byte, error) {
+	unlock, err := lockFile(path, false)
+	if err != nil {
+		return nil, fmt.Errorf("error locking %s: %w", path, err)
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(data, encryptedFileMagic) {
+		return data, nil
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%s is encrypted but no encryption key is configured", path)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	sealed := data[len(encryptedFileMagic):]
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%s is encrypted but too short to be valid: %w", path, ErrCorrupt)
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting %s, is the encryption key correct?: %w", path, err)
+	}
+	return plaintext, nil
+}
+
+// WriteFile replaces a state file, encrypting it when a key is given. The new content is written to a
+// temporary file and renamed over the old one, so a crash leaves either the old or the new file, never
+// a partial one. The previous version is kept at BackupPath.
+func WriteFile(path string, data []byte, key []byte) error {
+	if key != nil {
+		gcm, err := newGCM(key)
+		if err != nil {
+			return err
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return fmt.Errorf("error generating nonce: %w", err)
+		}
+
+		sealed := append(bytes.Clone(encryptedFileMagic), nonce...)
+		data = gcm.Seal(sealed, nonce, data, nil)
+	}
+
+	unlock, err := lockFile(path, true)
+	if err != nil {
+		return fmt.Errorf("error locking %s: %w", path, err)
+	}
+	defer unlock()
+
+	previous, err := os.ReadFile(path)
+	if err == nil {
+		if err := replaceFile(BackupPath(path), previous); err != nil {
+			return fmt.Errorf("error backing up %s: %w", path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return replaceFile(path, data)
+}
+
+// AppendFile appends to a file under the same lock as WriteFile, so appends never interleave with a rewrite.
+// Append-only files encrypt line by line with SealLine instead of as a whole.
+func AppendFile(path string, data []byte) error {
+	unlock, err := lockFile(path, true)
+	if err != nil {
+		return fmt.Errorf("error locking %s: %w", path, err)
+	}
+	defer unlock()
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// BackupPath is where WriteFile keeps the previous version of a file
+func BackupPath(path string) string {
+	return path + ".bak"
+}
+
+// ReadBackup reads the previous version of a file, for recovering from a corrupt file
+func ReadBackup(path string, key []byte) ([]byte, error) {
+	return ReadFile(BackupPath(path), key)
+}
+
+// Quarantine moves a corrupt file aside, keeping it for inspection, and returns where it was moved.
+// Without the corrupt file in place, the next write doesn't overwrite the good backup with it.
+func Quarantine(path string) (string, error) {
+	unlock, err := lockFile(path, true)
+	if err != nil {
+		return "", fmt.Errorf("error locking %s: %w", path, err)
+	}
+	defer unlock()
+
+	quarantinePath := fmt.Sprintf("%s.corrupt-%s", path, time.Now().Format("20060102-150405"))
+	return quarantinePath, os.Rename(path, quarantinePath)
+}
+
+// replaceFile atomically replaces a file by writing a synced temporary file and renaming it over the target
+func replaceFile(path string, data []byte) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return err
+	}
+
+	// Sync the directory so the rename itself survives a power loss
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+// Prefix marking an encrypted line in an append-only file
+const encryptedLinePrefix = "enc:"
+
+// SealLine encrypts a single line of an append-only file when a key is given, so lines can be appended without rewriting the file
+func SealLine(line []byte, key []byte) (string, error) {
+	if key == nil {
+		return string(line), nil
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("error generating nonce: %w", err)
+	}
+	return encryptedLinePrefix + base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, line, nil)), nil
+}
+
+// OpenLine reverses SealLine, passing plaintext lines through unchanged
+func OpenLine(line string, key []byte) ([]byte, error) {
+	encoded, isEncrypted := strings.CutPrefix(line, encryptedLinePrefix)
+	if !isEncrypted {
+		return []byte(line), nil
+	}
+	if key == nil {
+		return nil, errors.New("line is encrypted but no encryption key is configured")
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding encrypted line: %w: %w", ErrCorrupt, err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted line is too short to be valid: %w", ErrCorrupt)
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("encryption key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
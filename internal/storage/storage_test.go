@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorage(t *testing.T) {
+	t.Run("Round trip with encryption", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state.json")
+		key := DeriveKey("correct horse battery staple")
+
+		assert.NoError(t, WriteFile(path, []byte(`{"chat_id":"12345"}`), key))
+		raw, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(raw), "12345")
+
+		data, err := ReadFile(path, key)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"chat_id":"12345"}`, string(data))
+	})
+
+	t.Run("Wrong or missing key is rejected", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state.json")
+		assert.NoError(t, WriteFile(path, []byte("secret"), DeriveKey("one")))
+
+		_, err := ReadFile(path, DeriveKey("two"))
+		assert.Error(t, err)
+		_, err = ReadFile(path, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("Plaintext files stay readable with a key", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state.json")
+		assert.NoError(t, WriteFile(path, []byte("[]"), nil))
+
+		data, err := ReadFile(path, DeriveKey("new key"))
+		assert.NoError(t, err)
+		assert.Equal(t, "[]", string(data))
+	})
+
+	t.Run("Keep the previous version as a backup", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "state.json")
+		key := DeriveKey("backup key")
+		assert.NoError(t, WriteFile(path, []byte("first"), key))
+		_, err := ReadBackup(path, key)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+
+		assert.NoError(t, WriteFile(path, []byte("second"), key))
+		data, err := ReadFile(path, key)
+		assert.NoError(t, err)
+		assert.Equal(t, "second", string(data))
+		backup, err := ReadBackup(path, key)
+		assert.NoError(t, err)
+		assert.Equal(t, "first", string(backup))
+
+		temporary, err := filepath.Glob(filepath.Join(dir, "*.tmp-*"))
+		assert.NoError(t, err)
+		assert.Empty(t, temporary)
+	})
+
+	t.Run("Truncated encrypted files are corrupt", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state.json")
+		assert.NoError(t, os.WriteFile(path, []byte("AMULENC1abc"), 0o600))
+
+		_, err := ReadFile(path, DeriveKey("key"))
+		assert.ErrorIs(t, err, ErrCorrupt)
+
+		quarantinePath, err := Quarantine(path)
+		assert.NoError(t, err)
+		assert.FileExists(t, quarantinePath)
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("Seal and open single lines", func(t *testing.T) {
+		key := DeriveKey("line key")
+		sealed, err := SealLine([]byte(`{"sku":"SKU01"}`), key)
+		assert.NoError(t, err)
+		assert.NotContains(t, sealed, "SKU01")
+
+		line, err := OpenLine(sealed, key)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"sku":"SKU01"}`, string(line))
+
+		plain, err := OpenLine(`{"sku":"SKU02"}`, key)
+		assert.NoError(t, err)
+		assert.Equal(t, `{"sku":"SKU02"}`, string(plain))
+
+		_, err = OpenLine(sealed, nil)
+		assert.Error(t, err)
+		_, err = OpenLine("enc:!!!", key)
+		assert.ErrorIs(t, err, ErrCorrupt)
+	})
+
+	t.Run("Append to a file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "history.jsonl")
+		assert.NoError(t, AppendFile(path, []byte("first\n")))
+		assert.NoError(t, AppendFile(path, []byte("second\n")))
+
+		data, err := ReadFile(path, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "first\nsecond\n", string(data))
+	})
+}
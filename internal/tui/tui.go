@@ -0,0 +1,144 @@
+// Package tui renders a live terminal dashboard of the notifier for operators running it in a terminal multiplexer
+package tui
+
+import (
+	"amul-notifier/internal/bot"
+	"fmt"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Number of log lines kept for the log pane
+const logTailLimit = 200
+
+// LogTail is an io.Writer keeping the last log lines, meant to be passed to log.SetOutput while the dashboard runs
+type LogTail struct {
+	mu    sync.Mutex
+	lines []string
+	// Unterminated end of the last write
+	partial string
+}
+
+func (l *LogTail) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	text := l.partial + string(p)
+	lines := strings.Split(text, "\n")
+	l.partial = lines[len(lines)-1]
+	l.lines = append(l.lines, lines[:len(lines)-1]...)
+	if len(l.lines) > logTailLimit {
+		l.lines = l.lines[len(l.lines)-logTailLimit:]
+	}
+	return len(p), nil
+}
+
+// Last returns up to n of the most recent complete lines, oldest first
+func (l *LogTail) Last(n int) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n <= 0 {
+		return nil
+	}
+	return append([]string{}, l.lines[max(len(l.lines)-n, 0):]...)
+}
+
+// Update sent after every stock check
+type StatusUpdate struct {
+	Status      bot.Status
+	CheckedAt   time.Time
+	NextCheckAt time.Time
+}
+
+type tickMsg time.Time
+
+type model struct {
+	update   StatusUpdate
+	logs     *LogTail
+	now      time.Time
+	height   int
+	received bool
+}
+
+// New returns the dashboard program. Send it a StatusUpdate after every check and call Run to show it.
+func New(logs *LogTail) *tea.Program {
+	return tea.NewProgram(model{logs: logs, now: time.Now()}, tea.WithAltScreen())
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(time.Second, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+func (m model) Init() tea.Cmd {
+	return tick()
+}
+
+func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "q" || msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+	case tickMsg:
+		m.now = time.Time(msg)
+		return m, tick()
+	case StatusUpdate:
+		m.update = msg
+		m.received = true
+	}
+	return m, nil
+}
+
+func (m model) View() string {
+	var view strings.Builder
+	fmt.Fprintf(&view, "Amul Stock Notifier, store %s  (q to quit)\n", m.update.Status.Store)
+	if !m.received {
+		view.WriteString("Waiting for the first check...\n")
+	} else {
+		fmt.Fprintf(&view, "Last check %s, next check in %s\n",
+			m.update.CheckedAt.Format("15:04:05"), max(m.update.NextCheckAt.Sub(m.now), 0).Round(time.Second))
+	}
+
+	view.WriteString("\n")
+	table := tabwriter.NewWriter(&view, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "SKU\tSTOCK\tQTY\tPRICE\tNAME")
+	for _, product := range m.update.Status.Products {
+		stock := "out"
+		if product.InStock {
+			stock = "IN"
+		} else if product.Discontinued {
+			stock = "gone?"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%s\n", product.SKU, stock, product.Quantity, product.Price, product.Name)
+	}
+	table.Flush()
+
+	view.WriteString("\nRecent notifications\n")
+	notifications := m.update.Status.Notifications
+	if len(notifications) == 0 {
+		view.WriteString("  none yet\n")
+	}
+	for i := len(notifications) - 1; i >= max(len(notifications)-5, 0); i-- {
+		notification := notifications[i]
+		outcome := "sent"
+		if notification.Error != "" {
+			outcome = "FAILED: " + notification.Error
+		}
+		fmt.Fprintf(&view, "  %s  %-20s %-14s chat %s  %s\n", notification.At.Format("15:04:05"),
+			notification.NotificationType, notification.SKU, notification.ChatID, outcome)
+	}
+
+	view.WriteString("\nLog\n")
+	// Whatever height is left goes to the log pane
+	usedLines := strings.Count(view.String(), "\n")
+	for _, line := range m.logs.Last(m.height - usedLines - 1) {
+		view.WriteString("  " + line + "\n")
+	}
+	return view.String()
+}
@@ -0,0 +1,50 @@
+package tui
+
+import (
+	"amul-notifier/internal/bot"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDashboard(t *testing.T) {
+	t.Run("Keep the last complete log lines", func(t *testing.T) {
+		logs := &LogTail{}
+		fmt.Fprint(logs, "first\nsecond\nthi")
+		assert.Equal(t, []string{"first", "second"}, logs.Last(5))
+
+		fmt.Fprint(logs, "rd\n")
+		assert.Equal(t, []string{"second", "third"}, logs.Last(2))
+		assert.Empty(t, logs.Last(0))
+	})
+
+	t.Run("Render status, notifications and logs", func(t *testing.T) {
+		logs := &LogTail{}
+		fmt.Fprintln(logs, "Checking stock")
+		checkedAt := time.Date(2025, 5, 1, 10, 0, 0, 0, time.UTC)
+		dashboard := model{
+			logs:     logs,
+			now:      checkedAt.Add(90 * time.Second),
+			height:   40,
+			received: true,
+			update: StatusUpdate{
+				Status: bot.Status{
+					Store:         "gujarat",
+					Products:      []bot.ProductStatus{{SKU: "LASCP40_30", Name: "Rose Lassi", InStock: true, Quantity: 12, Price: 400}},
+					Notifications: []bot.NotificationRecord{{At: checkedAt, NotificationType: "in-stock", SKU: "LASCP40_30", ChatID: "42"}},
+				},
+				CheckedAt:   checkedAt,
+				NextCheckAt: checkedAt.Add(5 * time.Minute),
+			},
+		}
+
+		view := dashboard.View()
+		assert.Contains(t, view, "Last check 10:00:00, next check in 3m30s")
+		assert.Contains(t, view, "LASCP40_30  IN     12   400    Rose Lassi")
+		assert.True(t, strings.Contains(view, "in-stock") && strings.Contains(view, "chat 42  sent"))
+		assert.Contains(t, view, "  Checking stock\n")
+	})
+}
@@ -0,0 +1,166 @@
+// Package webhook posts JSON payloads to webhook URLs, signed with HMAC-SHA256 so receivers can verify them
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Headers carrying the signature and the Unix time it covers
+	SignatureHeader = "X-Amul-Signature"
+	TimestampHeader = "X-Amul-Timestamp"
+
+	maxAttempts = 3
+
+	// Payloads waiting for the background sender, further ones are dropped until it catches up
+	queueSize = 100
+)
+
+// ErrQueueFull is reported for payloads dropped because the background sender is too far behind
+var ErrQueueFull = errors.New("webhook queue is full, payload dropped")
+
+// Sender posts to every configured URL. A nil *Sender posts nothing.
+type Sender struct {
+	urls       []string
+	secret     string
+	httpClient *http.Client
+	// Wait before the nth retry, replaced in tests
+	backoff func(attempt int) time.Duration
+
+	queue      chan queuedPayload
+	startQueue sync.Once
+	queued     sync.WaitGroup
+}
+
+type queuedPayload struct {
+	payload []byte
+	onError func(error)
+}
+
+// New returns a sender for the URLs, signing payloads when secret is not empty
+func New(urls []string, secret string) *Sender {
+	return &Sender{
+		urls:       urls,
+		secret:     secret,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		backoff:    func(attempt int) time.Duration { return time.Duration(attempt) * time.Second },
+		queue:      make(chan queuedPayload, queueSize),
+	}
+}
+
+// Queue sends a payload like Send, but from a background goroutine so slow receivers and retries don't hold up
+// the caller. Payloads are sent one at a time in the order they were queued. A failed or dropped payload is
+// reported to onError.
+func (s *Sender) Queue(payload []byte, onError func(error)) {
+	if s == nil {
+		return
+	}
+	s.startQueue.Do(func() { go s.sendQueued() })
+
+	s.queued.Add(1)
+	select {
+	case s.queue <- queuedPayload{payload: payload, onError: onError}:
+	default:
+		s.queued.Done()
+		onError(ErrQueueFull)
+	}
+}
+
+func (s *Sender) sendQueued() {
+	for queued := range s.queue {
+		if err := s.Send(queued.payload); err != nil {
+			queued.onError(err)
+		}
+		s.queued.Done()
+	}
+}
+
+// Wait blocks until every queued payload has been sent or given up on
+func (s *Sender) Wait() {
+	if s == nil {
+		return
+	}
+	s.queued.Wait()
+}
+
+// Send posts a JSON payload to every URL, retrying each one on its own up to 3 times
+func (s *Sender) Send(payload []byte) error {
+	if s == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, url := range s.urls {
+		var err error
+		for attempt := range maxAttempts {
+			if attempt > 0 {
+				time.Sleep(s.backoff(attempt))
+			}
+			if err = s.post(url, payload, time.Now()); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", url, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Sender) post(url string, payload []byte, at time.Time) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.secret != "" {
+		timestamp := strconv.FormatInt(at.Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, Sign(s.secret, timestamp, payload))
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the signature header value: "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<payload>".
+// Covering the timestamp lets receivers reject replayed requests.
+func Sign(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a received request's signature and that its timestamp is within maxAge of now
+func Verify(secret, signature, timestamp string, payload []byte, maxAge time.Duration, now time.Time) error {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid webhook timestamp")
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > maxAge || age < -maxAge {
+		return errors.New("webhook timestamp is too old or in the future")
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, payload))) {
+		return errors.New("invalid webhook signature")
+	}
+	return nil
+}
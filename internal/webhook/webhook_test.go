@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSender(t *testing.T) {
+	t.Run("Post signed payloads that verify", func(t *testing.T) {
+		var received []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			received = body
+			err := Verify("s3cret", r.Header.Get(SignatureHeader), r.Header.Get(TimestampHeader), body, 5*time.Minute, time.Now())
+			assert.NoError(t, err)
+		}))
+		defer server.Close()
+
+		assert.NoError(t, New([]string{server.URL}, "s3cret").Send([]byte(`{"sku":"LASCP40_30"}`)))
+		assert.Equal(t, `{"sku":"LASCP40_30"}`, string(received))
+	})
+
+	t.Run("Retry failing URLs on their own", func(t *testing.T) {
+		failing, working := 0, 0
+		failingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			failing++
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer failingServer.Close()
+		workingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			working++
+		}))
+		defer workingServer.Close()
+
+		sender := New([]string{failingServer.URL, workingServer.URL}, "")
+		sender.backoff = func(int) time.Duration { return 0 }
+		assert.ErrorContains(t, sender.Send([]byte(`{}`)), "status 502")
+		assert.Equal(t, 3, failing)
+		assert.Equal(t, 1, working)
+
+		var nilSender *Sender
+		assert.NoError(t, nilSender.Send([]byte(`{}`)))
+	})
+
+	t.Run("Queue payloads without waiting for slow receivers", func(t *testing.T) {
+		release := make(chan struct{})
+		var received []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			body, _ := io.ReadAll(r.Body)
+			received = append(received, string(body))
+			if string(body) == `{"sku":"HPPCP01_24"}` {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+		}))
+		defer server.Close()
+
+		sender := New([]string{server.URL}, "")
+		sender.backoff = func(int) time.Duration { return 0 }
+		var errs []error
+		onError := func(err error) { errs = append(errs, err) }
+		sender.Queue([]byte(`{"sku":"LASCP40_30"}`), onError)
+		sender.Queue([]byte(`{"sku":"HPPCP01_24"}`), onError)
+		sender.Queue([]byte(`{"sku":"WPCCP03_01"}`), onError)
+
+		close(release)
+		sender.Wait()
+		assert.Equal(t, []string{`{"sku":"LASCP40_30"}`, `{"sku":"HPPCP01_24"}`, `{"sku":"HPPCP01_24"}`, `{"sku":"HPPCP01_24"}`, `{"sku":"WPCCP03_01"}`}, received)
+		if assert.Len(t, errs, 1) {
+			assert.ErrorContains(t, errs[0], "status 502")
+		}
+
+		var nilSender *Sender
+		nilSender.Queue([]byte(`{}`), onError)
+		nilSender.Wait()
+	})
+
+	t.Run("Reject tampered and stale requests", func(t *testing.T) {
+		now := time.Unix(1746000000, 0)
+		signature := Sign("s3cret", "1746000000", []byte(`{"in_stock":true}`))
+		assert.NoError(t, Verify("s3cret", signature, "1746000000", []byte(`{"in_stock":true}`), time.Minute, now))
+		assert.Error(t, Verify("s3cret", signature, "1746000000", []byte(`{"in_stock":false}`), time.Minute, now))
+		assert.Error(t, Verify("other", signature, "1746000000", []byte(`{"in_stock":true}`), time.Minute, now))
+		assert.Error(t, Verify("s3cret", signature, "1746000000", []byte(`{"in_stock":true}`), time.Minute, now.Add(time.Hour)))
+	})
+}
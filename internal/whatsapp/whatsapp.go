@@ -0,0 +1,88 @@
+// Package whatsapp sends notifications through the WhatsApp Business Cloud API
+package whatsapp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	graphAPIURL = "https://graph.facebook.com/v21.0"
+
+	// WhatsApp rejects longer text messages
+	maxMessageLength = 4096
+)
+
+// Client sends from one business phone number. A nil *Client sends nothing.
+type Client struct {
+	accessToken   string
+	phoneNumberID string
+	apiURL        string
+	httpClient    *http.Client
+}
+
+// New takes a Cloud API access token and the ID of the business phone number sending the messages
+func New(accessToken, phoneNumberID string) *Client {
+	return &Client{
+		accessToken:   accessToken,
+		phoneNumberID: phoneNumberID,
+		apiURL:        graphAPIURL,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Send delivers a text message to a phone number in international format, cut to the length limit.
+// WhatsApp only delivers free-form text to recipients who messaged the business number in the last 24 hours.
+func (c *Client) Send(to, message string) error {
+	if c == nil {
+		return nil
+	}
+
+	if len(message) > maxMessageLength {
+		message = message[:maxMessageLength-len("…")]
+		for !utf8.ValidString(message) {
+			message = message[:len(message)-1]
+		}
+		message += "…"
+	}
+	payload, err := json.Marshal(map[string]any{
+		"messaging_product": "whatsapp",
+		"recipient_type":    "individual",
+		"to":                to,
+		"type":              "text",
+		"text":              map[string]any{"body": message, "preview_url": true},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.apiURL+"/"+c.phoneNumberID+"/messages", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.accessToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		var response struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(body, &response)
+		return fmt.Errorf("whatsapp returned status %d: %s", resp.StatusCode, response.Error.Message)
+	}
+	return nil
+}
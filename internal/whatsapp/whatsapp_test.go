@@ -0,0 +1,63 @@
+package whatsapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSend(t *testing.T) {
+	t.Run("Send a text message from the business number", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/1234567890/messages", r.URL.Path)
+			assert.Equal(t, "Bearer access-token", r.Header.Get("Authorization"))
+
+			var payload struct {
+				To   string `json:"to"`
+				Type string `json:"type"`
+				Text struct {
+					Body string `json:"body"`
+				} `json:"text"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			assert.Equal(t, "919876543210", payload.To)
+			assert.Equal(t, "text", payload.Type)
+			assert.Equal(t, "*Stock Available!*", payload.Text.Body)
+			w.Write([]byte(`{"messaging_product":"whatsapp","messages":[{"id":"wamid.abc"}]}`))
+		}))
+		defer server.Close()
+
+		client := New("access-token", "1234567890")
+		client.apiURL = server.URL
+		assert.NoError(t, client.Send("919876543210", "*Stock Available!*"))
+	})
+
+	t.Run("Cut long messages and report API errors", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var payload struct {
+				Text struct {
+					Body string `json:"body"`
+				} `json:"text"`
+			}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+			assert.LessOrEqual(t, len(payload.Text.Body), maxMessageLength)
+			assert.True(t, utf8.ValidString(payload.Text.Body))
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"message":"(#131030) Recipient phone number not in allowed list","code":131030}}`))
+		}))
+		defer server.Close()
+
+		client := New("access-token", "1234567890")
+		client.apiURL = server.URL
+		err := client.Send("919876543210", strings.Repeat("✅", 2000))
+		assert.ErrorContains(t, err, "not in allowed list")
+
+		var nilClient *Client
+		assert.NoError(t, nilClient.Send("919876543210", "message"))
+	})
+}
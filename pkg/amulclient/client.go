@@ -0,0 +1,286 @@
+// Package amulclient is a client for the Amul shop (shop.amul.com) API, covering what is needed to check
+// product stock: store selection, session handling, product listing and pincode lookup.
+package amulclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBaseURL = "https://shop.amul.com"
+
+	// Sessions are renewed this long before the session cookie expires, well within the assumed lifetime below
+	sessionRefreshMargin = time.Hour
+
+	// Assumed session lifetime when the session cookie has no readable expiry
+	defaultSessionLifetime = 24 * time.Hour
+
+	productFields = "fields[name]=1&fields[brand]=1&fields[categories]=1&fields[collections]=1&fields[alias]=1&fields[sku]=1&fields[price]=1&fields[compare_price]=1&fields[original_price]=1&fields[images]=1&fields[metafields]=1&fields[discounts]=1&fields[catalog_only]=1&fields[is_catalog]=1&fields[seller]=1&fields[available]=1&fields[inventory_quantity]=1&fields[net_quantity]=1&fields[num_reviews]=1&fields[avg_rating]=1&fields[inventory_low_stock_quantity]=1&fields[inventory_allow_out_of_stock]=1"
+
+	// Path under which the shop serves product images
+	imagePath = "/s/62fa94df8c13af2e242eba16/"
+
+	browserUserAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/135.0.0.0 Safari/537.36"
+)
+
+// ErrPincodeNotServed is returned by ResolveSubstore when no Amul substore delivers to a pincode
+var ErrPincodeNotServed = errors.New("pincode is not served by any Amul store")
+
+// Product as listed by the products API
+type Product struct {
+	ID                string `json:"_id"`
+	Name              string `json:"name"`
+	Alias             string `json:"alias"`
+	SKU               string `json:"sku"`
+	Available         int    `json:"available"` // 1 if available, likely 0 otherwise
+	InventoryQuantity int    `json:"inventory_quantity"`
+	Price             int    `json:"price"`
+	ComparePrice      int    `json:"compare_price"` // MRP, higher than Price when discounted
+	// Main product image, from the first entry of the images list
+	Image ProductImage `json:"images"`
+}
+
+// ProductImage is the file name of a product image on the shop's media storage, or a full URL
+type ProductImage string
+
+// UnmarshalJSON keeps the first image of the list the API returns. Anything else reads as no image, the photo
+// is optional and mustn't fail decoding the whole product list.
+func (i *ProductImage) UnmarshalJSON(data []byte) error {
+	var images []struct {
+		Image string `json:"image"`
+	}
+	*i = ""
+	if err := json.Unmarshal(data, &images); err != nil {
+		return nil
+	}
+	if len(images) > 0 {
+		*i = ProductImage(images[0].Image)
+	}
+	return nil
+}
+
+// URL returns the absolute image URL, empty when the product has no image
+func (i ProductImage) URL() string {
+	if i == "" || strings.HasPrefix(string(i), "https://") || strings.HasPrefix(string(i), "http://") {
+		return string(i)
+	}
+	return DefaultBaseURL + imagePath + strings.TrimPrefix(string(i), "/")
+}
+
+// Substore serving a pincode
+type Substore struct {
+	Pincode  string `json:"pincode"`
+	Substore string `json:"substore"`
+}
+
+// Client talks to the Amul shop API. Products are listed per store, which Amul keeps on the session,
+// so the client starts and renews sessions by itself. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+
+	mu sync.Mutex
+	// Store set on the current session, empty before the first session
+	store         string
+	sessionExpiry time.Time
+}
+
+// New returns a client for the given base URL, DefaultBaseURL when empty
+func New(baseURL string) (*Client, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Jar: jar, Timeout: 30 * time.Second},
+	}, nil
+}
+
+// Store returns the store set on the current session, empty before the first session
+func (c *Client) Store() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.store
+}
+
+// SessionExpiry returns when the current session cookie expires
+func (c *Client) SessionExpiry() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessionExpiry
+}
+
+// SetPreferences starts a new session and sets the store whose products it lists
+func (c *Client) SetPreferences(ctx context.Context, store string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.startSession(ctx, store)
+}
+
+// startSession gets a fresh session cookie and sets the store on it, callers must hold the lock
+func (c *Client) startSession(ctx context.Context, store string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/en/", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", browserUserAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	sessionExpiry := sessionCookieExpiry(resp)
+
+	payload, _ := json.Marshal(map[string]map[string]string{"data": {"store": store}})
+	req, err = http.NewRequestWithContext(ctx, http.MethodPut, c.baseURL+"/entity/ms.settings/_/setPreferences", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	c.setAPIHeaders(req)
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("origin", c.baseURL)
+	req.Header.Set("priority", "u=1, i")
+	req.Header.Set("sec-ch-ua", `"Chromium";v="135", "Not-A.Brand";v="8"`)
+	req.Header.Set("sec-ch-ua-mobile", "?0")
+	req.Header.Set("sec-ch-ua-platform", `"Linux"`)
+	req.Header.Set("sec-fetch-dest", "empty")
+	req.Header.Set("sec-fetch-mode", "cors")
+	req.Header.Set("sec-fetch-site", "same-origin")
+
+	resp, err = c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("setting store '%s' failed with status: %d", store, resp.StatusCode)
+	}
+
+	c.store = store
+	c.sessionExpiry = sessionExpiry
+	return nil
+}
+
+// sessionCookieExpiry reads the expiry of the jsessionid cookie, assuming a default lifetime when it is missing
+func sessionCookieExpiry(resp *http.Response) time.Time {
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "jsessionid" && !cookie.Expires.IsZero() {
+			return cookie.Expires
+		}
+	}
+	return time.Now().Add(defaultSessionLifetime)
+}
+
+// EnsureSession starts a session for the store unless the current one is for that store and not about to expire.
+// ListProducts calls it by itself, calling it first separates session setup from the product request.
+func (c *Client) EnsureSession(ctx context.Context, store string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ensureSession(ctx, store)
+}
+
+// ensureSession is EnsureSession for callers holding the lock
+func (c *Client) ensureSession(ctx context.Context, store string) error {
+	if store == "" {
+		store = c.store
+	}
+	if store == "" {
+		return errors.New("no store set, call SetPreferences first or pass a store")
+	}
+	if store == c.store && time.Now().Add(sessionRefreshMargin).Before(c.sessionExpiry) {
+		return nil
+	}
+	return c.startSession(ctx, store)
+}
+
+// ListProducts lists the products of a category (e.g. "protein") in a store, switching the session
+// to that store when needed. An empty store keeps the store of the current session. The session is held
+// for the whole request, so a concurrent call for another store can't switch it in between.
+func (c *Client) ListProducts(ctx context.Context, category, store string) ([]Product, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.ensureSession(ctx, store); err != nil {
+		return nil, err
+	}
+
+	query := productFields + "&filters[0][field]=categories&filters[0][value][0]=" + url.QueryEscape(category) +
+		"&filters[0][operator]=in&facets=true&facetgroup=default_category_facet&limit=100&total=1&start=0"
+	var response struct {
+		Data []Product `json:"data"`
+	}
+	if err := c.getJSON(ctx, "/api/1/entity/ms.products?"+query, &response); err != nil {
+		return nil, err
+	}
+	return response.Data, nil
+}
+
+// ResolveSubstore finds the substore delivering to a pincode
+func (c *Client) ResolveSubstore(ctx context.Context, pincode string) (Substore, error) {
+	query := "limit=50&filters[0][field]=pincode&filters[0][value]=" + url.QueryEscape(pincode) + "&filters[0][operator]=regex&cf_cache=1h"
+	var response struct {
+		Records []Substore `json:"records"`
+	}
+	if err := c.getJSON(ctx, "/entity/pincode?"+query, &response); err != nil {
+		return Substore{}, err
+	}
+
+	// The lookup is a prefix match, so only an exact pincode counts
+	for _, record := range response.Records {
+		if record.Pincode == pincode && record.Substore != "" {
+			return record, nil
+		}
+	}
+	return Substore{}, ErrPincodeNotServed
+}
+
+// getJSON performs a GET request against the API and decodes the JSON response
+func (c *Client) getJSON(ctx context.Context, path string, target any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	c.setAPIHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error performing request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API returned non-OK status: %s", resp.Status)
+	}
+	if err := json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("error parsing JSON response: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) setAPIHeaders(req *http.Request) {
+	req.Header.Set("accept", "application/json, text/plain, */*")
+	req.Header.Set("accept-language", "en-US,en;q=0.9")
+	req.Header.Set("frontend", "1")
+	req.Header.Set("referer", c.baseURL+"/")
+	req.Header.Set("user-agent", browserUserAgent)
+}
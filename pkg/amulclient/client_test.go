@@ -0,0 +1,153 @@
+package amulclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestShop fakes the shop API, recording the store of every setPreferences call
+func newTestShop(t *testing.T, storesSet *[]string) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /en/", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "jsessionid", Value: "session", Path: "/", Expires: time.Now().Add(240 * time.Hour)})
+	})
+	mux.HandleFunc("PUT /entity/ms.settings/_/setPreferences", func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Data struct {
+				Store string `json:"store"`
+			} `json:"data"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		if payload.Data.Store == "atlantis" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		*storesSet = append(*storesSet, payload.Data.Store)
+	})
+	mux.HandleFunc("GET /api/1/entity/ms.products", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("jsessionid"); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "protein", r.URL.Query().Get("filters[0][value][0]"))
+		w.Write([]byte(`{"data":[{"sku":"LASCP40_30","name":"Rose Lassi","available":1,"inventory_quantity":12,"price":400,"images":[{"image":"rose-lassi.jpg"},{"image":"rose-lassi-back.jpg"}]}]}`))
+	})
+	mux.HandleFunc("GET /entity/pincode", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"records":[{"pincode":"3800150","substore":"other"},{"pincode":"380015","substore":"gujarat-ahmedabad"}]}`))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient(t *testing.T) {
+	t.Run("List products with session handling", func(t *testing.T) {
+		storesSet := []string{}
+		client, err := New(newTestShop(t, &storesSet).URL)
+		assert.NoError(t, err)
+
+		_, err = client.ListProducts(context.Background(), "protein", "")
+		assert.Error(t, err)
+
+		products, err := client.ListProducts(context.Background(), "protein", "gujarat")
+		assert.NoError(t, err)
+		assert.Equal(t, []Product{{SKU: "LASCP40_30", Name: "Rose Lassi", Available: 1, InventoryQuantity: 12, Price: 400, Image: "rose-lassi.jpg"}}, products)
+
+		// The session is reused until the store changes
+		_, err = client.ListProducts(context.Background(), "protein", "")
+		assert.NoError(t, err)
+		_, err = client.ListProducts(context.Background(), "protein", "maharashtra")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"gujarat", "maharashtra"}, storesSet)
+		assert.Equal(t, "maharashtra", client.Store())
+		assert.True(t, client.SessionExpiry().After(time.Now().Add(200*time.Hour)))
+	})
+
+	t.Run("List each store's products under concurrent use", func(t *testing.T) {
+		// The shop keeps the store on the session, so products are listed for whichever store was set last
+		var mu sync.Mutex
+		sessionStore := ""
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /en/", func(w http.ResponseWriter, r *http.Request) {})
+		mux.HandleFunc("PUT /entity/ms.settings/_/setPreferences", func(w http.ResponseWriter, r *http.Request) {
+			var payload struct {
+				Data struct {
+					Store string `json:"store"`
+				} `json:"data"`
+			}
+			json.NewDecoder(r.Body).Decode(&payload)
+			mu.Lock()
+			sessionStore = payload.Data.Store
+			mu.Unlock()
+		})
+		mux.HandleFunc("GET /api/1/entity/ms.products", func(w http.ResponseWriter, r *http.Request) {
+			// Leave time for another call to switch the store before this one is answered
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			store := sessionStore
+			mu.Unlock()
+			w.Write([]byte(`{"data":[{"sku":"LASCP40_30","name":"` + store + `"}]}`))
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		client, err := New(server.URL)
+		assert.NoError(t, err)
+		var wg sync.WaitGroup
+		for i := range 20 {
+			store := []string{"gujarat", "maharashtra"}[i%2]
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				products, err := client.ListProducts(context.Background(), "protein", store)
+				if assert.NoError(t, err) && assert.Len(t, products, 1) {
+					assert.Equal(t, store, products[0].Name)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("Reject unknown stores", func(t *testing.T) {
+		storesSet := []string{}
+		client, err := New(newTestShop(t, &storesSet).URL)
+		assert.NoError(t, err)
+
+		assert.Error(t, client.SetPreferences(context.Background(), "atlantis"))
+		assert.Equal(t, "", client.Store())
+	})
+
+	t.Run("Resolve substores by exact pincode", func(t *testing.T) {
+		client, err := New(newTestShop(t, &[]string{}).URL)
+		assert.NoError(t, err)
+
+		substore, err := client.ResolveSubstore(context.Background(), "380015")
+		assert.NoError(t, err)
+		assert.Equal(t, "gujarat-ahmedabad", substore.Substore)
+
+		_, err = client.ResolveSubstore(context.Background(), "110001")
+		assert.ErrorIs(t, err, ErrPincodeNotServed)
+	})
+	t.Run("Unexpected images read as no image", func(t *testing.T) {
+		for _, images := range []string{`null`, `{}`, `"rose-lassi.jpg"`, `[]`, `[1]`} {
+			var product Product
+			assert.NoError(t, json.Unmarshal([]byte(`{"sku":"LASCP40_30","images":`+images+`}`), &product), images)
+			assert.Equal(t, "LASCP40_30", product.SKU)
+			assert.Equal(t, ProductImage(""), product.Image, images)
+		}
+	})
+
+	t.Run("Build product image URLs", func(t *testing.T) {
+		assert.Equal(t, "https://shop.amul.com/s/62fa94df8c13af2e242eba16/rose-lassi.jpg", ProductImage("rose-lassi.jpg").URL())
+		assert.Equal(t, "https://cdn.example.com/a.jpg", ProductImage("https://cdn.example.com/a.jpg").URL())
+		assert.Equal(t, "", ProductImage("").URL())
+	})
+}